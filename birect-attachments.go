@@ -0,0 +1,35 @@
+package birect
+
+import "github.com/marcuswestin/go-birect/internal/wire"
+
+// Attachment is a named piece of binary data that can be sent along with
+// requests, responses and messages, without having to encode it into their values.
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Internal
+///////////
+
+func toWireAttachments(attachments []Attachment) []*wire.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	wireAttachments := make([]*wire.Attachment, len(attachments))
+	for i, attachment := range attachments {
+		wireAttachments[i] = &wire.Attachment{Name: attachment.Name, Data: attachment.Data}
+	}
+	return wireAttachments
+}
+
+func fromWireAttachments(wireAttachments []*wire.Attachment) []Attachment {
+	if len(wireAttachments) == 0 {
+		return nil
+	}
+	attachments := make([]Attachment, len(wireAttachments))
+	for i, wireAttachment := range wireAttachments {
+		attachments[i] = Attachment{wireAttachment.Name, wireAttachment.Data}
+	}
+	return attachments
+}
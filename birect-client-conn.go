@@ -0,0 +1,142 @@
+package birect
+
+import "context"
+
+// The Client's Conn gets replaced whenever the client reconnects. These methods
+// forward to the current Conn, and are safe to call while a reconnect happens.
+// Requests sent while the client is reconnecting wait in the OfflineQueue, if enabled.
+
+// CurrentConn returns the client's current Conn.
+func (client *Client) CurrentConn() *Conn {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	return client.conn
+}
+
+// Conn returns the client's current Conn. It is kept for callers of the
+// Client's formerly embedded Conn.
+//
+// Deprecated: Use CurrentConn.
+func (client *Client) Conn() *Conn {
+	return client.CurrentConn()
+}
+
+// Info returns the Info of the client's current Conn. Every new Conn, e.g after
+// reconnecting, starts out with an empty Info.
+func (client *Client) Info() Info {
+	return client.CurrentConn().Info
+}
+
+// Request returns the upgrade request of the current Conn, which is always nil for clients. See Conn.Request.
+func (client *Client) Request() *ConnRequest {
+	return client.CurrentConn().Request()
+}
+
+// Log logs the given arguments, along with contextual information about the current Conn.
+func (client *Client) Log(args ...interface{}) {
+	client.CurrentConn().Log(args...)
+}
+
+// SendJSONReq sends a JSON request on the current Conn. See Conn.SendJSONReq.
+func (client *Client) SendJSONReq(name string, resValPtr interface{}, paramsObj interface{}, attachments ...Attachment) error {
+	return client.SendJSONReqContext(context.Background(), name, resValPtr, paramsObj, attachments...)
+}
+
+// SendJSONReqContext sends a JSON request on the current Conn. See Conn.SendJSONReqContext.
+func (client *Client) SendJSONReqContext(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments ...Attachment) error {
+	_, err := client.SendJSONReqWithAttachments(ctx, name, resValPtr, paramsObj, attachments)
+	return err
+}
+
+// SendJSONReqWithAttachments sends a JSON request on the current Conn. See Conn.SendJSONReqWithAttachments.
+func (client *Client) SendJSONReqWithAttachments(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments []Attachment) ([]Attachment, error) {
+	return client.sendIntercepted(ctx, name, resValPtr, func(ctx context.Context) ([]Attachment, error) {
+		conn, sent, err := client.reqConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer sent()
+		return conn.sendJSONReq(ctx, name, resValPtr, paramsObj, attachments, sent)
+	})
+}
+
+// SendJSONMessage sends a JSON message on the current Conn. See Conn.SendJSONMessage.
+func (client *Client) SendJSONMessage(name string, valueObj interface{}, attachments ...Attachment) error {
+	return client.CurrentConn().SendJSONMessage(name, valueObj, attachments...)
+}
+
+// SendJSONMessageContext sends a JSON message on the current Conn. See Conn.SendJSONMessageContext.
+func (client *Client) SendJSONMessageContext(ctx context.Context, name string, valueObj interface{}, attachments ...Attachment) error {
+	return client.CurrentConn().SendJSONMessageContext(ctx, name, valueObj, attachments...)
+}
+
+// SendProtoReq sends a proto request on the current Conn. See Conn.SendProtoReq.
+func (client *Client) SendProtoReq(name string, resValPtr Proto, paramsObj Proto, attachments ...Attachment) error {
+	return client.SendProtoReqContext(context.Background(), name, resValPtr, paramsObj, attachments...)
+}
+
+// SendProtoReqContext sends a proto request on the current Conn. See Conn.SendProtoReqContext.
+func (client *Client) SendProtoReqContext(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments ...Attachment) error {
+	_, err := client.SendProtoReqWithAttachments(ctx, name, resValPtr, paramsObj, attachments)
+	return err
+}
+
+// SendProtoReqWithAttachments sends a proto request on the current Conn. See Conn.SendProtoReqWithAttachments.
+func (client *Client) SendProtoReqWithAttachments(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments []Attachment) ([]Attachment, error) {
+	return client.sendIntercepted(ctx, name, resValPtr, func(ctx context.Context) ([]Attachment, error) {
+		conn, sent, err := client.reqConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer sent()
+		return conn.sendProtoReq(ctx, name, resValPtr, paramsObj, attachments, sent)
+	})
+}
+
+// SendProtoMessage sends a proto message on the current Conn. See Conn.SendProtoMessage.
+func (client *Client) SendProtoMessage(name string, valueObj Proto, attachments ...Attachment) error {
+	return client.CurrentConn().SendProtoMessage(name, valueObj, attachments...)
+}
+
+// SendProtoMessageContext sends a proto message on the current Conn. See Conn.SendProtoMessageContext.
+func (client *Client) SendProtoMessageContext(ctx context.Context, name string, valueObj Proto, attachments ...Attachment) error {
+	return client.CurrentConn().SendProtoMessageContext(ctx, name, valueObj, attachments...)
+}
+
+// SendTextReq sends a text request on the current Conn. See Conn.SendTextReq.
+func (client *Client) SendTextReq(name string, resTextPtr *string, paramsText string, attachments ...Attachment) error {
+	return client.SendTextReqContext(context.Background(), name, resTextPtr, paramsText, attachments...)
+}
+
+// SendTextReqContext sends a text request on the current Conn. See Conn.SendTextReqContext.
+func (client *Client) SendTextReqContext(ctx context.Context, name string, resTextPtr *string, paramsText string, attachments ...Attachment) error {
+	_, err := client.sendIntercepted(ctx, name, resTextPtr, func(ctx context.Context) ([]Attachment, error) {
+		conn, sent, err := client.reqConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer sent()
+		return nil, conn.sendTextReq(ctx, name, resTextPtr, paramsText, attachments, sent)
+	})
+	return err
+}
+
+// SendStreamJSONReq sends a streaming JSON request on the current Conn. See Conn.SendStreamJSONReq.
+func (client *Client) SendStreamJSONReq(ctx context.Context, name string, paramsObj interface{}) (*JSONStreamReader, error) {
+	return client.CurrentConn().SendStreamJSONReq(ctx, name, paramsObj)
+}
+
+// SendStreamProtoReq sends a streaming proto request on the current Conn. See Conn.SendStreamProtoReq.
+func (client *Client) SendStreamProtoReq(ctx context.Context, name string, paramsObj Proto) (*ProtoStreamReader, error) {
+	return client.CurrentConn().SendStreamProtoReq(ctx, name, paramsObj)
+}
+
+// OpenStream opens a bidirectional stream on the current Conn. See Conn.OpenStream.
+func (client *Client) OpenStream(name string) (*Stream, error) {
+	return client.CurrentConn().OpenStream(name)
+}
+
+// OpenStreamContext opens a bidirectional stream on the current Conn. See Conn.OpenStreamContext.
+func (client *Client) OpenStreamContext(ctx context.Context, name string) (*Stream, error) {
+	return client.CurrentConn().OpenStreamContext(ctx, name)
+}
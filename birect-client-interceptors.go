@@ -0,0 +1,91 @@
+package birect
+
+import (
+	"context"
+	"sync"
+)
+
+// Interceptor wraps every request sent with a Client's SendJSONReq, SendProtoReq and
+// SendTextReq methods. Interceptors may edit req.Metadata before calling invoke to
+// send the request, and inspect the response or error afterwards. They may also call
+// invoke several times to retry, or return without calling it to short-circuit.
+//
+//	client.Intercept(func(req *birect.OutgoingReq, invoke birect.InvokeFunc) error {
+//		req.Metadata["Authorization"] = "Bearer " + token
+//		return invoke()
+//	})
+type Interceptor func(req *OutgoingReq, invoke InvokeFunc) error
+
+// InvokeFunc continues sending a request. See Interceptor.
+type InvokeFunc func() error
+
+// OutgoingReq describes a request that is being sent, for Interceptors.
+type OutgoingReq struct {
+	Name string
+	// Metadata is sent along with the request. Interceptors may modify it.
+	Metadata Metadata
+	// ResValPtr is the value that the response gets parsed into,
+	// i.e it holds the response once invoke has returned without error.
+	ResValPtr interface{}
+	ctx       context.Context
+}
+
+// Context returns the context that the request was sent with.
+func (r *OutgoingReq) Context() context.Context {
+	return r.ctx
+}
+
+// Intercept adds interceptors to wrap all outgoing requests with. Interceptors run in
+// the order they were added, i.e the first interceptor wraps all the others.
+func (client *Client) Intercept(interceptors ...Interceptor) {
+	client.interceptors.add(interceptors)
+}
+
+// Internal
+///////////
+
+// interceptorChain is safe for concurrent use.
+type interceptorChain struct {
+	mutex        *sync.Mutex
+	interceptors []Interceptor
+}
+
+func newInterceptorChain() *interceptorChain {
+	return &interceptorChain{&sync.Mutex{}, nil}
+}
+
+func (i *interceptorChain) add(interceptors []Interceptor) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.interceptors = append(i.interceptors[:len(i.interceptors):len(i.interceptors)], interceptors...)
+}
+
+func (i *interceptorChain) get() []Interceptor {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.interceptors
+}
+
+// sendIntercepted runs send through the client's interceptors. send gets called with
+// a context that carries the request metadata, every time an interceptor calls invoke.
+func (client *Client) sendIntercepted(ctx context.Context, name string, resValPtr interface{}, send func(ctx context.Context) ([]Attachment, error)) (resAttachments []Attachment, err error) {
+	interceptors := client.interceptors.get()
+	if len(interceptors) == 0 {
+		return send(ctx)
+	}
+	req := &OutgoingReq{name, MetadataFromContext(ctx).Copy(), resValPtr, ctx}
+	var next func(i int) InvokeFunc
+	next = func(i int) InvokeFunc {
+		if i == len(interceptors) {
+			return func() (sendErr error) {
+				resAttachments, sendErr = send(ContextWithMetadata(req.ctx, req.Metadata))
+				return sendErr
+			}
+		}
+		return func() error {
+			return interceptors[i](req, next(i+1))
+		}
+	}
+	err = next(0)()
+	return resAttachments, err
+}
@@ -0,0 +1,122 @@
+package birect
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OfflineQueue configures queueing of requests that are sent while a Client is
+// reconnecting. Queued requests are sent in order once the client has reconnected.
+// Up to Size requests get queued, after which requests fail with ErrOfflineQueueFull.
+// Requests that spend longer than MaxAge in the queue fail with ErrRequestExpired.
+// Queueing is disabled if Size is 0, and queued requests never expire if MaxAge is 0.
+type OfflineQueue struct {
+	Size   int
+	MaxAge time.Duration
+}
+
+// Internal
+///////////
+
+type queuedReq struct {
+	connChan chan *Conn // Receives the Conn to send on, or nil if the client gave up reconnecting
+	sentChan chan struct{}
+	sentOnce *sync.Once
+}
+
+func (q *queuedReq) sent() {
+	q.sentOnce.Do(func() { close(q.sentChan) })
+}
+
+// reqConn returns the Conn to send a request on. While the client is reconnecting,
+// reqConn queues the request and waits until it is its turn to be sent. Callers must
+// call sent once they have written the request, or given up on sending it.
+func (client *Client) reqConn(ctx context.Context) (conn *Conn, sent func(), err error) {
+	req, conn, err := client.enqueue()
+	if req == nil {
+		return conn, func() {}, err
+	}
+	return client.awaitTurn(ctx, req)
+}
+
+// enqueue adds a request to the offline queue while the client is reconnecting. If the
+// client is connected, or does not queue requests, enqueue returns the Conn to send on instead.
+func (client *Client) enqueue() (req *queuedReq, conn *Conn, err error) {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	if client.connected || client.OfflineQueue.Size == 0 || client.isClosed() {
+		return nil, client.conn, nil
+	}
+	if len(client.queue) >= client.OfflineQueue.Size {
+		return nil, nil, ErrOfflineQueueFull
+	}
+	req = &queuedReq{make(chan *Conn, 1), make(chan struct{}), &sync.Once{}}
+	client.queue = append(client.queue, req)
+	return req, nil, nil
+}
+
+// awaitTurn waits until flushQueue hands req a Conn to send on.
+func (client *Client) awaitTurn(ctx context.Context, req *queuedReq) (conn *Conn, sent func(), err error) {
+	var expired <-chan time.Time
+	if client.OfflineQueue.MaxAge > 0 {
+		timer := time.NewTimer(client.OfflineQueue.MaxAge)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case conn = <-req.connChan:
+	case <-expired:
+		err = ErrRequestExpired
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-client.closedChan:
+		err = ErrConnClosed
+	}
+	if err != nil && !client.dequeue(req) {
+		// The request was handed a Conn while it gave up. Let it fail on the Conn instead.
+		conn, err = <-req.connChan, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if conn == nil {
+		req.sent()
+		return nil, nil, ErrConnClosed
+	}
+	return conn, req.sent, nil
+}
+
+// dequeue removes req from the queue. It returns false if req has already been handed a Conn.
+func (client *Client) dequeue(req *queuedReq) bool {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	for i, queued := range client.queue {
+		if queued == req {
+			client.queue = append(client.queue[:i], client.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// flushQueue sends the queued requests one at a time, in order, on conn. Requests that
+// get queued while flushing are sent as well, before the client is marked as connected.
+// A nil conn fails all the queued requests.
+func (client *Client) flushQueue(conn *Conn) {
+	for {
+		client.connMutex.Lock()
+		if len(client.queue) == 0 {
+			// conn may have been lost already, while flushing
+			client.connected = conn != nil && conn != client.lostConn
+			client.connMutex.Unlock()
+			return
+		}
+		req := client.queue[0]
+		client.queue = client.queue[1:]
+		client.connMutex.Unlock()
+
+		req.connChan <- conn
+		<-req.sentChan
+	}
+}
@@ -1,7 +1,10 @@
 package birect
 
 import (
+	"math/rand"
 	"net/url"
+	"sync"
+	"time"
 
 	errs "github.com/marcuswestin/go-errs"
 	"github.com/marcuswestin/go-ws"
@@ -9,13 +12,72 @@ import (
 
 // Client is used register request handlers (for requests sent from the server),
 // and to send requests to the server.
+//
+// A Client reconnects by itself when its connection drops. Each reconnection
+// replaces the client's Conn with a new Conn, which dispatches to the same handlers.
+// Use CurrentConn to get the Conn that the client is currently connected with.
+//
+// Client used to embed its *Conn. Since the Conn gets replaced on reconnect, it is no
+// longer embedded: client.Conn is now a method, client.Info is now client.Info(), and
+// Conn methods that the Client does not forward are called on CurrentConn instead.
 type Client struct {
-	jsonReqHandlerMap
-	protoReqHandlerMap
-	*Conn
+	handlerMaps
+	connConfig
 
-	// Temporary
-	OnDisconnectHack func()
+	// Backoff controls the delay between reconnection attempts.
+	Backoff Backoff
+	// OnDisconnect gets called with the old Conn whenever the connection drops.
+	OnDisconnect func(conn *Conn)
+	// OnReconnect gets called with the new Conn once the client has reconnected.
+	OnReconnect func(conn *Conn)
+	// OfflineQueue controls queueing of requests while the client is reconnecting.
+	OfflineQueue OfflineQueue
+
+	address      string
+	connMutex    *sync.Mutex
+	connected    bool
+	lostConn     *Conn
+	queue        []*queuedReq
+	closedChan   chan struct{}
+	closeOnce    *sync.Once
+	interceptors *interceptorChain
+	conn         *Conn // Guarded by connMutex
+}
+
+// Backoff configures the delay between a Client's reconnection attempts. The first
+// delay is MinDelay, and every failed attempt multiplies it by Factor, up to MaxDelay.
+// Jitter randomizes each delay by up to the given fraction, e.g 0.2 for +/- 20%.
+// If MaxAttempts is non-zero the Client gives up and closes after that many failed attempts.
+type Backoff struct {
+	MinDelay    time.Duration
+	MaxDelay    time.Duration
+	Factor      float64
+	Jitter      float64
+	MaxAttempts int
+}
+
+// DefaultBackoff is the Backoff of clients returned by Connect.
+var DefaultBackoff = Backoff{
+	MinDelay: 100 * time.Millisecond,
+	MaxDelay: 30 * time.Second,
+	Factor:   2,
+	Jitter:   0.2,
+}
+
+// Delay returns how long to wait before the given reconnection attempt, starting at 0.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.MinDelay)
+	for i := 0; i < attempt && delay < float64(b.MaxDelay); i++ {
+		delay *= b.Factor
+	}
+	if delay > float64(b.MaxDelay) {
+		delay = float64(b.MaxDelay)
+	}
+	delay += delay * b.Jitter * (rand.Float64()*2 - 1)
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
 }
 
 // Connect connects to a birect server at address
@@ -25,31 +87,161 @@ func Connect(address string) (client *Client, err error) {
 		return
 	}
 	client = &Client{
-		jsonReqHandlerMap:  make(jsonReqHandlerMap),
-		protoReqHandlerMap: make(protoReqHandlerMap),
-		Conn:               nil,
+		handlerMaps:  newHandlerMaps(),
+		connConfig:   newConnConfig(),
+		Backoff:      DefaultBackoff,
+		address:      address,
+		connMutex:    &sync.Mutex{},
+		closedChan:   make(chan struct{}),
+		closeOnce:    &sync.Once{},
+		interceptors: newInterceptorChain(),
+	}
+	client.onGoingAway = client.goingAway
+	if _, err = client.connect(true); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Close closes the client's connection and stops it from reconnecting.
+// Requests waiting in the offline queue fail with ErrConnClosed.
+func (client *Client) Close() error {
+	client.markClosed()
+	return client.CurrentConn().Close()
+}
+
+// Internal
+///////////
+
+// connect opens a new connection to the client's address, and returns once
+// it has either connected or failed to connect. The initial connection becomes
+// the client's Conn before any of its other events get handled, so that a
+// disconnect right away makes the client reconnect.
+func (client *Client) connect(initial bool) (*Conn, error) {
+	connChan := make(chan *Conn, 1)
+	errChan := make(chan error, 1)
+	failed := func(err error) {
+		if err == nil {
+			err = errs.New(errs.Info{"Address": client.address}, "Unable to connect")
+		}
+		select {
+		case errChan <- err:
+		default:
+		}
 	}
-	wsConnChan := make(chan *ws.Conn)
-	ws.Connect(address, func(event *ws.Event, conn *ws.Conn) {
-		client.Log("Client event:", event)
+	var conn *Conn // Only accessed by this connection's event handler
+	ws.Connect(client.address, func(event *ws.Event, wsConn *ws.Conn) {
+		if conn != nil {
+			conn.Log("Client event:", event)
+		}
 		switch event.Type {
 		case ws.Connected:
-			wsConnChan <- conn
+			conn = newConn(wsConn, client.handlerMaps, client.connConfig)
+			conn.startHeartbeat(client.heartbeat.get())
+			if initial {
+				client.setConnected(conn)
+			}
+			connChan <- conn
 		case ws.BinaryMessage:
-			client.Conn.readAndHandleWireWrapperReader(event)
+			conn.readAndHandleWireWrapperReader(event)
 		case ws.Disconnected:
-			client.Log("TODO: reconnect logic (Disconnected)")
-			if client.OnDisconnectHack != nil {
-				client.OnDisconnectHack()
+			if conn == nil {
+				failed(event.Error)
+				return
 			}
+			client.setDisconnected(conn)
+			conn.close()
+			go client.reconnect(conn)
 		case ws.NetError:
-			client.Log("NetError")
+			if conn == nil {
+				failed(event.Error)
+				return
+			}
+			conn.Log("NetError")
 		default:
 			panic("TODO Handle event: " + event.String())
 		}
 	})
-	client.Conn = newConn(<-wsConnChan, client.jsonReqHandlerMap, client.protoReqHandlerMap)
-	return
+	select {
+	case conn := <-connChan:
+		return conn, nil
+	case err := <-errChan:
+		return nil, err
+	}
+}
+
+func (client *Client) reconnect(oldConn *Conn) {
+	if client.OnDisconnect != nil {
+		client.OnDisconnect(oldConn)
+	}
+	for attempt := 0; client.Backoff.MaxAttempts == 0 || attempt < client.Backoff.MaxAttempts; attempt++ {
+		select {
+		case <-client.closedChan:
+			client.flushQueue(nil)
+			return
+		case <-time.After(client.Backoff.Delay(attempt)):
+		}
+		conn, err := client.connect(false)
+		if err != nil {
+			oldConn.Log("Reconnect failed", "Attempt:", attempt, err)
+			continue
+		}
+		if !client.replaceConn(conn) {
+			conn.Close()
+			client.flushQueue(nil)
+			return
+		}
+		conn.Log("Reconnected", "Attempt:", attempt)
+		client.resubscribe(conn)
+		client.flushQueue(conn)
+		if client.OnReconnect != nil {
+			client.OnReconnect(conn)
+		}
+		return
+	}
+	oldConn.Log("Giving up reconnecting", "Attempts:", client.Backoff.MaxAttempts)
+	client.markClosed()
+	client.flushQueue(nil)
+}
+
+func (client *Client) markClosed() {
+	client.closeOnce.Do(func() { close(client.closedChan) })
+}
+
+func (client *Client) isClosed() bool {
+	select {
+	case <-client.closedChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (client *Client) setConnected(conn *Conn) {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	client.conn = conn
+	client.connected = true
+}
+
+func (client *Client) setDisconnected(conn *Conn) {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	client.connected = false
+	client.lostConn = conn
+}
+
+// replaceConn swaps in the given conn, unless the client has been closed.
+func (client *Client) replaceConn(conn *Conn) bool {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	select {
+	case <-client.closedChan:
+		return false
+	default:
+		client.conn = conn
+		return true
+	}
 }
 
 func fixAddress(address string) (string, error) {
@@ -1,9 +1,8 @@
 package birect
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	runtimeDebug "runtime/debug"
 
 	"github.com/marcuswestin/go-birect/internal/wire"
 	"github.com/marcuswestin/go-errs"
@@ -14,20 +13,100 @@ type JSONReqHandler func(req *JSONReq) (resValue interface{}, err error)
 
 // SendJSONReq sends a request for the JSONReqHandler with the given `name`, along with the
 // given paramsObj. When the server responds, SendJSONReq will parse the response into resValPtr.
-func (c *Conn) SendJSONReq(name string, resValPtr interface{}, paramsObj interface{}) (err error) {
-	data, err := json.Marshal(paramsObj)
+// Any given attachments are sent along with the request.
+func (c *Conn) SendJSONReq(name string, resValPtr interface{}, paramsObj interface{}, attachments ...Attachment) (err error) {
+	return c.SendJSONReqContext(context.Background(), name, resValPtr, paramsObj, attachments...)
+}
+
+// SendJSONReqContext is like SendJSONReq, but returns as soon as ctx is done. The context's
+// deadline and cancellation are propagated to the handler's JSONReq.Context().
+func (c *Conn) SendJSONReqContext(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments ...Attachment) (err error) {
+	_, err = c.SendJSONReqWithAttachments(ctx, name, resValPtr, paramsObj, attachments)
+	return
+}
+
+// SendJSONReqWithAttachments is like SendJSONReqContext, but also returns the attachments
+// that the handler added to the response with JSONReq.AttachToResponse.
+func (c *Conn) SendJSONReqWithAttachments(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments []Attachment) (resAttachments []Attachment, err error) {
+	return c.sendJSONReq(ctx, name, resValPtr, paramsObj, attachments, nil)
+}
+
+// JSONMessageHandler functions get called on every json message
+type JSONMessageHandler func(msg *JSONMessage)
+
+// SendJSONMessage sends a one-way message for the JSONMessageHandler with the given `name`,
+// along with the given valueObj and attachments. SendJSONMessage does not wait for the message to be handled.
+func (c *Conn) SendJSONMessage(name string, valueObj interface{}, attachments ...Attachment) (err error) {
+	return c.SendJSONMessageContext(context.Background(), name, valueObj, attachments...)
+}
+
+// SendJSONMessageContext is like SendJSONMessage, but also sends the trace context of ctx, if any,
+// which the handler reads with JSONMessage.Context().
+func (c *Conn) SendJSONMessageContext(ctx context.Context, name string, valueObj interface{}, attachments ...Attachment) (err error) {
+	data, err := json.Marshal(valueObj)
 	if err != nil {
 		return
 	}
-	reqID := c.nextReqID()
-	wireReq := &wire.Request{Type: wire.DataType_JSON, Name: name, ReqId: uint32(reqID), Data: data}
-	return c.sendRequestAndWaitForResponse(reqID, wireReq, resValPtr)
+	return c.sendMessage(&wire.Message{Type: wire.DataType_JSON, Name: name, Data: data, Attachments: toWireAttachments(attachments), Traceparent: traceparentFromContext(ctx)})
+}
+
+// JSONMessage wraps a message sent via SendJSONMessage. Use ParseValue to access the JSON values.
+type JSONMessage struct {
+	Conn        *Conn
+	Name        string
+	data        []byte
+	attachments []Attachment
+	ctx         context.Context
+}
+
+// Attachments returns the attachments that were sent along with the message.
+func (j *JSONMessage) Attachments() []Attachment {
+	return j.attachments
+}
+
+// Context returns the message context, which carries the sender's trace context, if any.
+func (j *JSONMessage) Context() context.Context {
+	return j.ctx
+}
+
+// ParseValue parses the JSONMessage values into the given valuePtr.
+// valuePtr should be a pointer to a struct that can be JSON-parsed.
+func (j *JSONMessage) ParseValue(valuePtr interface{}) {
+	err := json.Unmarshal(j.data, valuePtr)
+	if err != nil {
+		panic(errs.Wrap(err, nil, "Unable to parse value"))
+	}
+}
+
+// JSONString returns the message data as a JSON string
+func (j *JSONMessage) JSONString() string {
+	return string(j.data)
 }
 
 // JSONReq wraps a request sent via SendJSONReq. Use ParseParams to access the JSON values.
 type JSONReq struct {
-	Conn *Conn
-	data []byte
+	Conn           *Conn
+	data           []byte
+	ctx            context.Context
+	attachments    []Attachment
+	resAttachments []Attachment
+}
+
+// Attachments returns the attachments that were sent along with the request.
+func (j *JSONReq) Attachments() []Attachment {
+	return j.attachments
+}
+
+// AttachToResponse adds the given attachments to the response. Requesters
+// receive them with SendJSONReqWithAttachments.
+func (j *JSONReq) AttachToResponse(attachments ...Attachment) {
+	j.resAttachments = append(j.resAttachments, attachments...)
+}
+
+// Context returns the request context. It is cancelled when the requester
+// cancels the request, or when the requester's deadline passes.
+func (j *JSONReq) Context() context.Context {
+	return j.ctx
 }
 
 // ParseParams parses the JSONReq values into the given valuePtr.
@@ -57,45 +136,49 @@ func (m jsonReqHandlerMap) HandleJSONReq(reqName string, handler JSONReqHandler)
 	m[reqName] = handler
 }
 
-func (c *Conn) handleJSONWireReq(wireReq *wire.Request) {
-	// Find handler
-	handler, exists := c.jsonReqHandlerMap[wireReq.Name]
+type jsonMessageHandlerMap map[string]JSONMessageHandler
+
+func (m jsonMessageHandlerMap) HandleJSONMessage(msgName string, handler JSONMessageHandler) {
+	m[msgName] = handler
+}
+
+func (c *Conn) sendJSONReq(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments []Attachment, sent func()) (resAttachments []Attachment, err error) {
+	data, err := json.Marshal(paramsObj)
+	if err != nil {
+		return
+	}
+	reqID := c.nextReqID()
+	wireReq := &wire.Request{Type: wire.DataType_JSON, Name: name, ReqId: uint32(reqID), Data: data, Attachments: toWireAttachments(attachments)}
+	return c.sendRequestAndWaitForResponse(ctx, reqID, wireReq, resValPtr, sent)
+}
+
+func (c *Conn) handleJSONWireMessage(wireMsg *wire.Message) {
+	handler, exists := c.jsonMessageHandlerMap[wireMsg.Name]
 	if !exists {
-		c.sendErrorResponse(wireReq, errs.New(nil, "Missing request handler"))
+		c.Log("Missing message handler", wireMsg.Name)
 		return
 	}
-	// Execute handler
-	defer func() {
-		if r := recover(); r != nil {
-			err, ok := r.(error)
-			if !ok {
-				err = errs.New(errs.Info{"Recovery": r})
-			}
-			stack := string(runtimeDebug.Stack())
-			c.Log("Error while handling request", wireReq.Name, err, stack)
-			c.sendErrorResponse(wireReq, errs.Wrap(err, errs.Info{"Name": wireReq.Name, "Data": wireReq.Data}))
+	handler(&JSONMessage{c, wireMsg.Name, wireMsg.Data, fromWireAttachments(wireMsg.Attachments), withTrace(context.Background(), wireMsg.Traceparent, nil)})
+}
+
+func newJSONReq(ctx context.Context, c *Conn, wireReq *wire.Request) *JSONReq {
+	return &JSONReq{c, wireReq.Data, ctx, fromWireAttachments(wireReq.Attachments), nil}
+}
+
+func (c *Conn) handleJSONWireReq(ctx context.Context, wireReq *wire.Request) {
+	jsonReq := newJSONReq(ctx, c, wireReq)
+	resVal, err := c.runReqHandler(ctx, wireReq.Name, func() (interface{}, error) {
+		handler, exists := c.jsonReqHandlerMap[wireReq.Name]
+		if !exists {
+			return nil, errs.New(nil, "Missing request handler")
 		}
-	}()
-	resVal, err := _runJSONHandler(handler, &JSONReq{c, wireReq.Data})
+		return handler(jsonReq)
+	})
 	if err != nil {
-		c.sendErrorResponse(wireReq, errs.Wrap(err, errs.Info{"HandlerName": wireReq.Name}))
+		c.sendErrorResponse(wireReq, wrapHandlerError(err, errs.Info{"HandlerName": wireReq.Name}))
 		return
 	}
-	// Send response
-	c.sendResponse(wireReq, &jsonRes{resVal})
-}
-
-func _runJSONHandler(handler JSONReqHandler, jsonReq *JSONReq) (res interface{}, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if rErr, ok := r.(error); ok {
-				err = rErr
-			} else {
-				err = errs.New(nil, fmt.Sprint(r))
-			}
-		}
-	}()
-	return handler(jsonReq)
+	c.sendResponse(wireReq, &jsonRes{resVal}, jsonReq.resAttachments)
 }
 
 type jsonRes struct {
@@ -0,0 +1,251 @@
+package birect
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// Internal
+///////////
+
+// pendingReqs keeps track of the requests that are waiting for a response.
+// It is safe for concurrent use.
+type pendingReqs struct {
+	mutex    *sync.Mutex
+	resChans map[reqID]resChan
+	closed   bool
+}
+
+func newPendingReqs() *pendingReqs {
+	return &pendingReqs{&sync.Mutex{}, make(map[reqID]resChan, 1), false}
+}
+
+// add registers a request that is about to be sent. The returned channel
+// receives the response, or gets closed if the Conn closes first.
+func (p *pendingReqs) add(id reqID) (resChan, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return nil, ErrConnClosed
+	}
+	resChan := make(resChan, 1)
+	p.resChans[id] = resChan
+	return resChan, nil
+}
+
+// remove stops waiting for the response to the given request.
+func (p *pendingReqs) remove(id reqID) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.resChans, id)
+}
+
+// resolve delivers wireRes to its waiting request. It returns false if
+// there is no such request, e.g. for stray or duplicate responses.
+func (p *pendingReqs) resolve(wireRes *wire.Response) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	id := reqID(wireRes.ReqId)
+	resChan, exists := p.resChans[id]
+	if !exists {
+		return false
+	}
+	delete(p.resChans, id)
+	resChan <- wireRes
+	return true
+}
+
+// close fails all pending requests, as well as any requests added later.
+func (p *pendingReqs) len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.resChans)
+}
+
+func (p *pendingReqs) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	for id, resChan := range p.resChans {
+		delete(p.resChans, id)
+		close(resChan)
+	}
+}
+
+// pendingStreams keeps track of the streaming requests that are waiting for chunks.
+// It is safe for concurrent use.
+type pendingStreams struct {
+	mutex   *sync.Mutex
+	streams map[reqID]*streamFrames
+	closed  bool
+}
+
+// streamFrames receives the chunks and end of a streaming request. done gets
+// closed, with err set, if the stream is stopped before its end frame arrives.
+type streamFrames struct {
+	frames chan streamFrame
+	done   chan struct{}
+	err    error
+}
+type streamFrame struct {
+	chunk *wire.StreamChunk
+	end   *wire.StreamEnd
+}
+
+// Number of chunks a stream's handler may send before the requester grants more credit. The
+// requester buffers that many chunks, and grants credit in batches as its reader consumes them.
+// Streams whose handler ignores the credit and overflows the buffer fail with ErrStreamOverflow.
+const (
+	streamBufferSize  = 64
+	streamCreditBatch = streamBufferSize / 2
+)
+
+// deliverResult tells what became of a frame handed to pendingStreams.deliver.
+type deliverResult int
+
+const (
+	frameDelivered deliverResult = iota
+	frameUnknown                 // There is no such stream
+	frameOverflow                // The stream's buffer was full, and the stream has been removed
+)
+
+func newPendingStreams() *pendingStreams {
+	return &pendingStreams{&sync.Mutex{}, make(map[reqID]*streamFrames), false}
+}
+
+func (p *pendingStreams) add(id reqID) (*streamFrames, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return nil, ErrConnClosed
+	}
+	// One extra slot, so that the end frame always fits
+	frames := &streamFrames{make(chan streamFrame, streamBufferSize+1), make(chan struct{}), nil}
+	p.streams[id] = frames
+	return frames, nil
+}
+
+func (p *pendingStreams) len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.streams)
+}
+
+// remove stops the given stream, and makes it fail with err.
+func (p *pendingStreams) remove(id reqID, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.removeLocked(id, err)
+}
+func (p *pendingStreams) removeLocked(id reqID, err error) {
+	frames, exists := p.streams[id]
+	if !exists {
+		return
+	}
+	delete(p.streams, id)
+	frames.err = err
+	close(frames.done)
+}
+
+// deliver hands a frame to its stream. deliver never blocks, since it gets called from the
+// Conn's read loop: a stream that gets sent more chunks than it has granted credit for is
+// removed instead, and fails with ErrStreamOverflow once its buffered chunks have been read.
+func (p *pendingStreams) deliver(id reqID, frame streamFrame) deliverResult {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	frames, exists := p.streams[id]
+	if !exists {
+		return frameUnknown
+	}
+	// Only the read loop delivers frames, so the buffer can not fill up between the check and the send
+	if frame.end == nil && len(frames.frames) >= streamBufferSize {
+		p.removeLocked(id, ErrStreamOverflow)
+		return frameOverflow
+	}
+	frames.frames <- frame
+	if frame.end != nil {
+		// No more frames after the end
+		delete(p.streams, id)
+	}
+	return frameDelivered
+}
+
+// close fails all pending streams, as well as any streams added later.
+func (p *pendingStreams) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	for id := range p.streams {
+		p.removeLocked(id, ErrConnClosed)
+	}
+}
+
+// sendCredits counts how many more chunks may be sent on a stream before
+// the receiver grants more. It is safe for concurrent use.
+type sendCredits struct {
+	mutex     *sync.Mutex
+	available uint32
+	granted   chan struct{} // Closed and replaced whenever credits are granted
+}
+
+func newSendCredits(available uint32) *sendCredits {
+	return &sendCredits{&sync.Mutex{}, available, make(chan struct{})}
+}
+
+// take waits until a credit is available, and uses it up.
+func (s *sendCredits) take(ctx context.Context) error {
+	for {
+		s.mutex.Lock()
+		if s.available > 0 {
+			s.available--
+			s.mutex.Unlock()
+			return nil
+		}
+		granted := s.granted
+		s.mutex.Unlock()
+		select {
+		case <-granted:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *sendCredits) grant(credit uint32) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.available += credit
+	close(s.granted)
+	s.granted = make(chan struct{})
+}
+
+// streamCredits keeps track of the send credits of the streams being handled.
+// It is safe for concurrent use.
+type streamCredits struct {
+	mutex   *sync.Mutex
+	credits map[reqID]*sendCredits
+}
+
+func newStreamCredits() *streamCredits {
+	return &streamCredits{&sync.Mutex{}, make(map[reqID]*sendCredits)}
+}
+
+func (s *streamCredits) add(id reqID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credits[id] = newSendCredits(streamBufferSize)
+}
+
+func (s *streamCredits) get(id reqID) *sendCredits {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.credits[id]
+}
+
+func (s *streamCredits) remove(id reqID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.credits, id)
+}
@@ -1,7 +1,7 @@
 package birect
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/marcuswestin/go-birect/internal/wire"
@@ -14,22 +14,107 @@ type Proto proto.Message
 // ProtoReqHandler functions get called on every proto request
 type ProtoReqHandler func(req *ProtoReq) (resValue Proto, err error)
 
+// ProtoReqHandlerRegistry is implemented by Handler and Client, for use with generated service code.
+type ProtoReqHandlerRegistry interface {
+	HandleProtoReq(reqName string, handler ProtoReqHandler)
+}
+
+// ProtoReqSender is implemented by Conn and Client, for use with generated service code.
+type ProtoReqSender interface {
+	SendProtoReqContext(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments ...Attachment) error
+}
+
 // SendProtoReq sends a request for the ProtoReqHandler with the given `name`, along with the
 // given paramsObj. When the server responds, SendProtoReq will parse the response into resValPtr.
-func (c *Conn) SendProtoReq(name string, resValPtr Proto, paramsObj Proto) (err error) {
-	data, err := proto.Marshal(paramsObj)
+// Any given attachments are sent along with the request.
+func (c *Conn) SendProtoReq(name string, resValPtr Proto, paramsObj Proto, attachments ...Attachment) (err error) {
+	return c.SendProtoReqContext(context.Background(), name, resValPtr, paramsObj, attachments...)
+}
+
+// SendProtoReqContext is like SendProtoReq, but returns as soon as ctx is done. The context's
+// deadline and cancellation are propagated to the handler's ProtoReq.Context().
+func (c *Conn) SendProtoReqContext(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments ...Attachment) (err error) {
+	_, err = c.SendProtoReqWithAttachments(ctx, name, resValPtr, paramsObj, attachments)
+	return
+}
+
+// SendProtoReqWithAttachments is like SendProtoReqContext, but also returns the attachments
+// that the handler added to the response with ProtoReq.AttachToResponse.
+func (c *Conn) SendProtoReqWithAttachments(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments []Attachment) (resAttachments []Attachment, err error) {
+	return c.sendProtoReq(ctx, name, resValPtr, paramsObj, attachments, nil)
+}
+
+// ProtoMessageHandler functions get called on every proto message
+type ProtoMessageHandler func(msg *ProtoMessage)
+
+// SendProtoMessage sends a one-way message for the ProtoMessageHandler with the given `name`,
+// along with the given valueObj and attachments. SendProtoMessage does not wait for the message to be handled.
+func (c *Conn) SendProtoMessage(name string, valueObj Proto, attachments ...Attachment) (err error) {
+	return c.SendProtoMessageContext(context.Background(), name, valueObj, attachments...)
+}
+
+// SendProtoMessageContext is like SendProtoMessage, but also sends the trace context of ctx, if any,
+// which the handler reads with ProtoMessage.Context().
+func (c *Conn) SendProtoMessageContext(ctx context.Context, name string, valueObj Proto, attachments ...Attachment) (err error) {
+	data, err := proto.Marshal(valueObj)
 	if err != nil {
 		return
 	}
-	reqID := c.nextReqID()
-	wireReq := &wire.Request{Type: wire.DataType_Proto, Name: name, ReqId: uint32(reqID), Data: data}
-	return c.sendRequestAndWaitForResponse(reqID, wireReq, resValPtr)
+	return c.sendMessage(&wire.Message{Type: wire.DataType_Proto, Name: name, Data: data, Attachments: toWireAttachments(attachments), Traceparent: traceparentFromContext(ctx)})
+}
+
+// ProtoMessage wraps a message sent via SendProtoMessage. Use ParseValue to access the proto values.
+type ProtoMessage struct {
+	*Conn
+	Name        string
+	data        []byte
+	attachments []Attachment
+	ctx         context.Context
+}
+
+// Attachments returns the attachments that were sent along with the message.
+func (p *ProtoMessage) Attachments() []Attachment {
+	return p.attachments
+}
+
+// Context returns the message context, which carries the sender's trace context, if any.
+func (p *ProtoMessage) Context() context.Context {
+	return p.ctx
+}
+
+// ParseValue parses the ProtoMessage values into the given valuePtr.
+// valuePtr should be a pointer to a struct that implements Proto.message.
+func (p *ProtoMessage) ParseValue(valuePtr Proto) {
+	err := proto.Unmarshal(p.data, valuePtr)
+	if err != nil {
+		panic(errs.Wrap(err, nil, "Unable to parse value"))
+	}
 }
 
 // ProtoReq wraps a request sent via SendProtoReq. Use ParseParams to access the proto values.
 type ProtoReq struct {
 	*Conn
-	data []byte
+	data           []byte
+	ctx            context.Context
+	attachments    []Attachment
+	resAttachments []Attachment
+}
+
+// Attachments returns the attachments that were sent along with the request.
+func (p *ProtoReq) Attachments() []Attachment {
+	return p.attachments
+}
+
+// AttachToResponse adds the given attachments to the response. Requesters
+// receive them with SendProtoReqWithAttachments.
+func (p *ProtoReq) AttachToResponse(attachments ...Attachment) {
+	p.resAttachments = append(p.resAttachments, attachments...)
+}
+
+// Context returns the request context. It is cancelled when the requester
+// cancels the request, or when the requester's deadline passes.
+func (p *ProtoReq) Context() context.Context {
+	return p.ctx
 }
 
 // ParseParams parses the ProtoReq values into the given valuePtr.
@@ -50,36 +135,54 @@ func (m protoReqHandlerMap) HandleProtoReq(reqName string, handler ProtoReqHandl
 	m[reqName] = handler
 }
 
-func (c *Conn) handleProtoWireReq(wireReq *wire.Request) {
-	// Find handler
-	handler, exists := c.protoReqHandlerMap[wireReq.Name]
+type protoMessageHandlerMap map[string]ProtoMessageHandler
+
+func (m protoMessageHandlerMap) HandleProtoMessage(msgName string, handler ProtoMessageHandler) {
+	m[msgName] = handler
+}
+
+func (c *Conn) sendProtoReq(ctx context.Context, name string, resValPtr Proto, paramsObj Proto, attachments []Attachment, sent func()) (resAttachments []Attachment, err error) {
+	data, err := proto.Marshal(paramsObj)
+	if err != nil {
+		return
+	}
+	reqID := c.nextReqID()
+	wireReq := &wire.Request{Type: wire.DataType_Proto, Name: name, ReqId: uint32(reqID), Data: data, Attachments: toWireAttachments(attachments)}
+	return c.sendRequestAndWaitForResponse(ctx, reqID, wireReq, resValPtr, sent)
+}
+
+func (c *Conn) handleProtoWireMessage(wireMsg *wire.Message) {
+	handler, exists := c.protoMessageHandlerMap[wireMsg.Name]
 	if !exists {
-		c.sendErrorResponse(wireReq, errs.New(nil, "Missing request handler"))
+		c.Log("Missing message handler", wireMsg.Name)
 		return
 	}
-	// Execute handler
-	resVal, err := _runProtoHandler(handler, &ProtoReq{c, wireReq.Data})
+	handler(&ProtoMessage{c, wireMsg.Name, wireMsg.Data, fromWireAttachments(wireMsg.Attachments), withTrace(context.Background(), wireMsg.Traceparent, nil)})
+}
+
+func newProtoReq(ctx context.Context, c *Conn, wireReq *wire.Request) *ProtoReq {
+	return &ProtoReq{c, wireReq.Data, ctx, fromWireAttachments(wireReq.Attachments), nil}
+}
+
+func (c *Conn) handleProtoWireReq(ctx context.Context, wireReq *wire.Request) {
+	protoReq := newProtoReq(ctx, c, wireReq)
+	res, err := c.runReqHandler(ctx, wireReq.Name, func() (interface{}, error) {
+		handler, exists := c.protoReqHandlerMap[wireReq.Name]
+		if !exists {
+			return nil, errs.New(nil, "Missing request handler")
+		}
+		return handler(protoReq)
+	})
 	if err != nil {
-		c.sendErrorResponse(wireReq, err)
+		c.sendErrorResponse(wireReq, wrapHandlerError(err, errs.Info{"HandlerName": wireReq.Name}))
 		return
 	}
-	// Send response
-	c.sendResponse(wireReq, &protoRes{resVal})
-}
-
-func _runProtoHandler(handler ProtoReqHandler, protoReq *ProtoReq) (res Proto, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			if errsErr, ok := r.(errs.Err); ok {
-				err = errsErr
-			} else if stdErr, ok := r.(error); ok {
-				err = errs.Wrap(stdErr, nil)
-			} else {
-				err = errs.New(nil, fmt.Sprint(r))
-			}
-		}
-	}()
-	return handler(protoReq)
+	resVal, ok := res.(Proto)
+	if !ok && res != nil {
+		c.sendErrorResponse(wireReq, errs.New(errs.Info{"HandlerName": wireReq.Name, "Result": res}, "Expected Proto result"))
+		return
+	}
+	c.sendResponse(wireReq, &protoRes{resVal}, protoReq.resAttachments)
 }
 
 type protoRes struct {
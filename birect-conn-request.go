@@ -0,0 +1,44 @@
+package birect
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+)
+
+// ConnRequest is a read-only snapshot of the HTTP request that a Conn was upgraded from.
+type ConnRequest struct {
+	RemoteAddr string
+	Host       string
+	URL        *url.URL
+	Header     http.Header
+	TLS        *tls.ConnectionState
+}
+
+// Request returns a snapshot of the HTTP request that the Conn was upgraded from,
+// captured when the Conn was registered. It returns nil for a Client's Conns.
+func (c *Conn) Request() *ConnRequest {
+	return c.request
+}
+
+// Query returns the parsed query parameters of the request URL.
+func (r *ConnRequest) Query() url.Values {
+	return r.URL.Query()
+}
+
+// Cookie returns the named cookie of the request, or http.ErrNoCookie if there is none.
+func (r *ConnRequest) Cookie(name string) (*http.Cookie, error) {
+	return (&http.Request{Header: r.Header}).Cookie(name)
+}
+
+// Internal
+///////////
+
+func newConnRequest(r *http.Request) *ConnRequest {
+	header := make(http.Header, len(r.Header))
+	for key, values := range r.Header {
+		header[key] = append([]string(nil), values...)
+	}
+	reqURL := *r.URL
+	return &ConnRequest{r.RemoteAddr, r.Host, &reqURL, header, r.TLS}
+}
@@ -0,0 +1,323 @@
+package birect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
+
+// StreamJSONReqHandler functions get called on every streaming json request.
+// The handler may call req.Send any number of times. The stream ends when the handler returns.
+type StreamJSONReqHandler func(req *StreamJSONReq) (err error)
+
+// StreamProtoReqHandler functions get called on every streaming proto request.
+// The handler may call req.Send any number of times. The stream ends when the handler returns.
+type StreamProtoReqHandler func(req *StreamProtoReq) (err error)
+
+// SendStreamJSONReq sends a request for the StreamJSONReqHandler with the given `name`, along
+// with the given paramsObj. Use the returned JSONStreamReader to read the streamed chunks.
+func (c *Conn) SendStreamJSONReq(ctx context.Context, name string, paramsObj interface{}) (reader *JSONStreamReader, err error) {
+	data, err := json.Marshal(paramsObj)
+	if err != nil {
+		return
+	}
+	reqID := c.nextReqID()
+	wireReq := &wire.Request{Type: wire.DataType_JSON, Name: name, ReqId: uint32(reqID), Data: data, Stream: true}
+	stream, err := c.sendStreamRequest(ctx, reqID, wireReq)
+	if err != nil {
+		return
+	}
+	return &JSONStreamReader{stream}, nil
+}
+
+// SendStreamProtoReq sends a request for the StreamProtoReqHandler with the given `name`, along
+// with the given paramsObj. Use the returned ProtoStreamReader to read the streamed chunks.
+func (c *Conn) SendStreamProtoReq(ctx context.Context, name string, paramsObj Proto) (reader *ProtoStreamReader, err error) {
+	data, err := proto.Marshal(paramsObj)
+	if err != nil {
+		return
+	}
+	reqID := c.nextReqID()
+	wireReq := &wire.Request{Type: wire.DataType_Proto, Name: name, ReqId: uint32(reqID), Data: data, Stream: true}
+	stream, err := c.sendStreamRequest(ctx, reqID, wireReq)
+	if err != nil {
+		return
+	}
+	return &ProtoStreamReader{stream}, nil
+}
+
+// StreamJSONReq wraps a request sent via SendStreamJSONReq. Use Send to stream chunks back.
+type StreamJSONReq struct {
+	*JSONReq
+	wireReq *wire.Request
+}
+
+// Send sends valueObj as the next chunk of the stream.
+func (s *StreamJSONReq) Send(valueObj interface{}) error {
+	return s.Conn.sendStreamChunk(s.ctx, s.wireReq, &jsonRes{valueObj})
+}
+
+// StreamProtoReq wraps a request sent via SendStreamProtoReq. Use Send to stream chunks back.
+type StreamProtoReq struct {
+	*ProtoReq
+	wireReq *wire.Request
+}
+
+// Send sends valueObj as the next chunk of the stream.
+func (s *StreamProtoReq) Send(valueObj Proto) error {
+	return s.Conn.sendStreamChunk(s.ctx, s.wireReq, &protoRes{valueObj})
+}
+
+// JSONStreamReader reads the chunks of a stream started with SendStreamJSONReq.
+type JSONStreamReader struct {
+	*streamReader
+}
+
+// Recv parses the next chunk into valuePtr. Recv returns io.EOF once the stream has
+// ended, or the handler's error if it ended with one.
+func (r *JSONStreamReader) Recv(valuePtr interface{}) error {
+	return r.recv(valuePtr)
+}
+
+// Close stops reading the stream, and cancels the handler's context if it is still running.
+// Close must not be called concurrently with Recv - cancel the request context instead.
+func (r *JSONStreamReader) Close() {
+	r.close()
+}
+
+// ProtoStreamReader reads the chunks of a stream started with SendStreamProtoReq.
+type ProtoStreamReader struct {
+	*streamReader
+}
+
+// Recv parses the next chunk into valuePtr. Recv returns io.EOF once the stream has
+// ended, or the handler's error if it ended with one.
+func (r *ProtoStreamReader) Recv(valuePtr Proto) error {
+	return r.recv(valuePtr)
+}
+
+// Close stops reading the stream, and cancels the handler's context if it is still running.
+// Close must not be called concurrently with Recv - cancel the request context instead.
+func (r *ProtoStreamReader) Close() {
+	r.close()
+}
+
+// Internal
+///////////
+
+type streamJSONReqHandlerMap map[string]StreamJSONReqHandler
+
+func (m streamJSONReqHandlerMap) HandleStreamJSONReq(reqName string, handler StreamJSONReqHandler) {
+	m[reqName] = handler
+}
+
+type streamProtoReqHandlerMap map[string]StreamProtoReqHandler
+
+func (m streamProtoReqHandlerMap) HandleStreamProtoReq(reqName string, handler StreamProtoReqHandler) {
+	m[reqName] = handler
+}
+
+// Internal - Requesting side
+/////////////////////////////
+
+type streamReader struct {
+	conn     *Conn
+	ctx      context.Context
+	reqID    reqID
+	frames   *streamFrames
+	err      error
+	consumed uint32          // Chunks read since credit was last granted
+	endSpan  func(err error) // Called once the stream has ended
+}
+
+func (c *Conn) sendStreamRequest(ctx context.Context, reqID reqID, wireReq *wire.Request) (*streamReader, error) {
+	if err := setReqContext(ctx, wireReq); err != nil {
+		return nil, err
+	}
+	endSpan := c.startClientSpan(ctx, wireReq.Name, &wireReq.Traceparent)
+	frames, err := c.streams.add(reqID)
+	if err != nil {
+		endSpan(err)
+		return nil, err
+	}
+	c.Log("STREAM REQ", wireReq.Name, "ReqID:", reqID, "len:", len(wireReq.Data))
+	err = c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_Request{Request: wireReq},
+	})
+	if err != nil {
+		c.streams.remove(reqID, err)
+		endSpan(err)
+		return nil, errs.Wrap(err, nil)
+	}
+	return &streamReader{c, ctx, reqID, frames, nil, 0, endSpan}, nil
+}
+
+func (s *streamReader) recv(valuePtr interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	var frame streamFrame
+	select {
+	case frame = <-s.frames.frames:
+	default:
+		// Only give up once all buffered frames have been read
+		select {
+		case frame = <-s.frames.frames:
+		case <-s.frames.done:
+			return s.end(s.frames.err)
+		case <-s.ctx.Done():
+			s.stop(s.ctx.Err())
+			return s.err
+		}
+	}
+	if frame.end != nil {
+		if frame.end.IsError {
+			return s.end(newResponseError(frame.end.Error, frame.end.Data))
+		}
+		return s.end(io.EOF)
+	}
+	s.grantCredit()
+	return errs.Wrap(decodeWireData(frame.chunk.Type, frame.chunk.Data, valuePtr), nil)
+}
+
+// end makes all further reads return err, and ends the stream's span.
+func (s *streamReader) end(err error) error {
+	s.err = err
+	if err == io.EOF {
+		s.endSpan(nil)
+	} else {
+		s.endSpan(err)
+	}
+	return err
+}
+
+// grantCredit lets the handler send more chunks once enough buffered ones have been read.
+func (s *streamReader) grantCredit() {
+	s.consumed++
+	if s.consumed < streamCreditBatch {
+		return
+	}
+	s.conn.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamCredit{StreamCredit: &wire.StreamCredit{ReqId: uint32(s.reqID), Credit: s.consumed}},
+	})
+	s.consumed = 0
+}
+
+func (s *streamReader) close() {
+	if s.err != nil {
+		return
+	}
+	s.stop(io.EOF)
+}
+
+func (s *streamReader) stop(err error) {
+	s.end(err)
+	s.conn.Log("CANCEL STREAM", "ReqID:", s.reqID, err)
+	s.conn.streams.remove(s.reqID, err)
+	s.conn.sendCancel(s.reqID)
+}
+
+func (c *Conn) handleStreamChunk(wireChunk *wire.StreamChunk) {
+	switch c.streams.deliver(reqID(wireChunk.ReqId), streamFrame{chunk: wireChunk}) {
+	case frameUnknown:
+		c.Log("Dropping stream chunk for unknown request", wireChunk.ReqId)
+	case frameOverflow:
+		c.Log("CANCEL STREAM - handler sent more chunks than granted", "ReqID:", wireChunk.ReqId)
+		c.sendCancel(reqID(wireChunk.ReqId))
+	}
+}
+func (c *Conn) handleStreamEnd(wireEnd *wire.StreamEnd) {
+	if c.streams.deliver(reqID(wireEnd.ReqId), streamFrame{end: wireEnd}) != frameDelivered {
+		c.Log("Dropping stream end for unknown request", wireEnd.ReqId)
+	}
+}
+
+// Internal - Handling side
+///////////////////////////
+
+func (c *Conn) handleStreamRequest(wireReq *wire.Request) {
+	c.streamCredits.add(reqID(wireReq.ReqId))
+	switch wireReq.Type {
+	case wire.DataType_JSON:
+		c.startHandler(wireReq, c.handleStreamJSONWireReq, c.sendStreamEnd)
+	case wire.DataType_Proto:
+		c.startHandler(wireReq, c.handleStreamProtoWireReq, c.sendStreamEnd)
+	default:
+		c.sendStreamEnd(wireReq, errs.New(errs.Info{"Type": wireReq.Type}, "Bad wireReq.Type"))
+	}
+}
+
+func (c *Conn) handleStreamJSONWireReq(ctx context.Context, wireReq *wire.Request) {
+	_, err := c.runReqHandler(ctx, wireReq.Name, func() (interface{}, error) {
+		handler, exists := c.streamJSONReqHandlerMap[wireReq.Name]
+		if !exists {
+			return nil, errs.New(nil, "Missing request handler")
+		}
+		return nil, handler(&StreamJSONReq{newJSONReq(ctx, c, wireReq), wireReq})
+	})
+	c.sendStreamEnd(wireReq, wrapHandlerError(err, errs.Info{"HandlerName": wireReq.Name}))
+}
+
+func (c *Conn) handleStreamProtoWireReq(ctx context.Context, wireReq *wire.Request) {
+	_, err := c.runReqHandler(ctx, wireReq.Name, func() (interface{}, error) {
+		handler, exists := c.streamProtoReqHandlerMap[wireReq.Name]
+		if !exists {
+			return nil, errs.New(nil, "Missing request handler")
+		}
+		return nil, handler(&StreamProtoReq{newProtoReq(ctx, c, wireReq), wireReq})
+	})
+	c.sendStreamEnd(wireReq, wrapHandlerError(err, errs.Info{"HandlerName": wireReq.Name}))
+}
+
+func (c *Conn) sendStreamChunk(ctx context.Context, wireReq *wire.Request, response response) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := response.encode()
+	if err != nil {
+		return errs.Wrap(err, nil, "Unable to encode stream chunk")
+	}
+	if credits := c.streamCredits.get(reqID(wireReq.ReqId)); credits != nil {
+		// Wait for the requester to make room, rather than overflowing its buffer
+		if err := credits.take(ctx); err != nil {
+			return err
+		}
+	}
+	c.trackReqData(wireReq, len(data))
+	return c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamChunk{StreamChunk: &wire.StreamChunk{
+			ReqId: wireReq.ReqId,
+			Type:  response.dataType(),
+			Data:  data,
+		}},
+	})
+}
+
+func (c *Conn) sendStreamEnd(wireReq *wire.Request, err error) {
+	c.streamCredits.remove(reqID(wireReq.ReqId))
+	wireEnd := &wire.StreamEnd{ReqId: wireReq.ReqId}
+	if err != nil {
+		c.Log("Stream ERROR", wireReq.ReqId, err)
+		wireEnd.IsError = true
+		wireEnd.Type = wire.DataType_Text
+		wireEnd.Error = c.toWireError(err)
+		wireEnd.Data = []byte(wireEnd.Error.Message)
+	}
+	c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamEnd{StreamEnd: wireEnd},
+	})
+	c.trackReqDone(wireReq, len(wireEnd.Data), err)
+}
+
+func (c *Conn) handleStreamCredit(wireCredit *wire.StreamCredit) {
+	credits := c.streamCredits.get(reqID(wireCredit.ReqId))
+	if credits == nil {
+		c.Log("Dropping stream credit for unknown request", wireCredit.ReqId)
+		return
+	}
+	credits.grant(wireCredit.Credit)
+}
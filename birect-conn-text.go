@@ -0,0 +1,110 @@
+package birect
+
+import (
+	"context"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
+
+// TextReqHandler functions get called on every text request
+type TextReqHandler func(req *TextReq) (resText string, err error)
+
+// SendTextReq sends a request for the TextReqHandler with the given `name`, along with the
+// given paramsText. When the server responds, SendTextReq will set resTextPtr to the response text.
+// Any given attachments are sent along with the request.
+func (c *Conn) SendTextReq(name string, resTextPtr *string, paramsText string, attachments ...Attachment) (err error) {
+	return c.SendTextReqContext(context.Background(), name, resTextPtr, paramsText, attachments...)
+}
+
+// SendTextReqContext is like SendTextReq, but returns as soon as ctx is done. The context's
+// deadline and cancellation are propagated to the handler's TextReq.Context().
+func (c *Conn) SendTextReqContext(ctx context.Context, name string, resTextPtr *string, paramsText string, attachments ...Attachment) (err error) {
+	return c.sendTextReq(ctx, name, resTextPtr, paramsText, attachments, nil)
+}
+
+// TextReq wraps a request sent via SendTextReq. Use Text to access the request text.
+type TextReq struct {
+	Conn           *Conn
+	data           []byte
+	ctx            context.Context
+	attachments    []Attachment
+	resAttachments []Attachment
+}
+
+// Text returns the request params text
+func (t *TextReq) Text() string {
+	return string(t.data)
+}
+
+// Context returns the request context. It is cancelled when the requester
+// cancels the request, or when the requester's deadline passes.
+func (t *TextReq) Context() context.Context {
+	return t.ctx
+}
+
+// Attachments returns the attachments that were sent along with the request.
+func (t *TextReq) Attachments() []Attachment {
+	return t.attachments
+}
+
+// AttachToResponse adds the given attachments to the response.
+func (t *TextReq) AttachToResponse(attachments ...Attachment) {
+	t.resAttachments = append(t.resAttachments, attachments...)
+}
+
+// Internal
+///////////
+
+type textReqHandlerMap map[string]TextReqHandler
+
+func (m textReqHandlerMap) HandleTextReq(reqName string, handler TextReqHandler) {
+	m[reqName] = handler
+}
+
+func (c *Conn) sendTextReq(ctx context.Context, name string, resTextPtr *string, paramsText string, attachments []Attachment, sent func()) error {
+	reqID := c.nextReqID()
+	wireReq := &wire.Request{Type: wire.DataType_Text, Name: name, ReqId: uint32(reqID), Data: []byte(paramsText), Attachments: toWireAttachments(attachments)}
+	var resValPtr interface{}
+	if resTextPtr != nil {
+		resValPtr = resTextPtr
+	}
+	_, err := c.sendRequestAndWaitForResponse(ctx, reqID, wireReq, resValPtr, sent)
+	return err
+}
+
+func newTextReq(ctx context.Context, c *Conn, wireReq *wire.Request) *TextReq {
+	return &TextReq{c, wireReq.Data, ctx, fromWireAttachments(wireReq.Attachments), nil}
+}
+
+func (c *Conn) handleTextWireReq(ctx context.Context, wireReq *wire.Request) {
+	textReq := newTextReq(ctx, c, wireReq)
+	res, err := c.runReqHandler(ctx, wireReq.Name, func() (interface{}, error) {
+		handler, exists := c.textReqHandlerMap[wireReq.Name]
+		if !exists {
+			return nil, errs.New(nil, "Missing request handler")
+		}
+		return handler(textReq)
+	})
+	if err != nil {
+		c.sendErrorResponse(wireReq, wrapHandlerError(err, errs.Info{"HandlerName": wireReq.Name}))
+		return
+	}
+	resText, ok := res.(string)
+	if !ok {
+		c.sendErrorResponse(wireReq, errs.New(errs.Info{"HandlerName": wireReq.Name, "Result": res}, "Expected string result"))
+		return
+	}
+	c.sendResponse(wireReq, &textRes{resText}, textReq.resAttachments)
+}
+
+type textRes struct {
+	text string
+}
+
+func (t *textRes) encode() ([]byte, error) {
+	return []byte(t.text), nil
+}
+func (t *textRes) dataType() wire.DataType {
+	return wire.DataType_Text
+}
@@ -1,12 +1,16 @@
 package birect
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"io/ioutil"
 	"log"
+	runtimeDebug "runtime/debug"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/marcuswestin/go-birect/internal/wire"
@@ -29,12 +33,28 @@ func LogToStdout() {
 // Conn represents a persistent bi-directional connection between
 // a birect client and a birect server.
 type Conn struct {
-	Info      Info
-	wsConn    *ws.Conn
-	lastReqID reqID
-	resChans  map[reqID]resChan
-	jsonReqHandlerMap
-	protoReqHandlerMap
+	Info            Info
+	id              uint64 // Assigned by the Handler when it registers the Conn. Zero for a Client's Conns
+	authInfo        Info   // As returned by Authenticate. Never modified, so it is safe to read concurrently
+	wsConn          *ws.Conn
+	lastReqID       reqID
+	pending         *pendingReqs
+	streams         *pendingStreams
+	streamCredits   *streamCredits
+	lastStreamID    reqID
+	openedStreams   *pendingStreams
+	acceptedStreams *pendingStreams
+	liveStreams     *streamSet
+	cancelsMutex    *sync.Mutex
+	cancels         map[reqID]context.CancelFunc
+	request         *ConnRequest
+	slots           *reqSlots
+	liveness        *liveness
+	tracker         *reqTracker
+	publications    *publicationQueue
+	metricsCounted  bool // Set if ConnOpened got called for the Conn. Guarded by the Handler's connByWSConnMutex
+	handlerMaps
+	connConfig
 }
 
 // Log logs the given arguments, along with contextual information about the Conn.
@@ -42,14 +62,92 @@ func (c *Conn) Log(args ...interface{}) {
 	Log(c, args...)
 }
 
+// Close closes the underlying connection. Pending requests fail with ErrConnClosed.
+func (c *Conn) Close() error {
+	return c.wsConn.Close()
+}
+
 // Internal
 ///////////
 
 type reqID uint32
-type resChan chan *wire.Response
+type resChan chan *wire.Response // Closed when the Conn closes
 
-func newConn(wsConn *ws.Conn, jsonHandlers jsonReqHandlerMap, protoHandlers protoReqHandlerMap) *Conn {
-	return &Conn{newInfo(), wsConn, 0, make(map[reqID]resChan, 1), jsonHandlers, protoHandlers}
+func newConn(wsConn *ws.Conn, handlers handlerMaps, config connConfig) *Conn {
+	return &Conn{
+		Info:            newInfo(),
+		wsConn:          wsConn,
+		pending:         newPendingReqs(),
+		streams:         newPendingStreams(),
+		streamCredits:   newStreamCredits(),
+		openedStreams:   newPendingStreams(),
+		acceptedStreams: newPendingStreams(),
+		liveStreams:     newStreamSet(),
+		cancelsMutex:    &sync.Mutex{},
+		cancels:         make(map[reqID]context.CancelFunc),
+		slots:           config.limiter.newReqSlots(),
+		liveness:        newLiveness(),
+		tracker:         newReqTracker(),
+		publications:    newPublicationQueue(),
+		handlerMaps:     handlers,
+		connConfig:      config,
+	}
+}
+
+// handlerMaps holds all the handlers that a Conn dispatches to. It is shared
+// between a Handler or Client and all of its Conns.
+type handlerMaps struct {
+	jsonReqHandlerMap
+	protoReqHandlerMap
+	textReqHandlerMap
+	jsonMessageHandlerMap
+	protoMessageHandlerMap
+	streamJSONReqHandlerMap
+	streamProtoReqHandlerMap
+	streamHandlerMap
+}
+
+func newHandlerMaps() handlerMaps {
+	return handlerMaps{
+		make(jsonReqHandlerMap),
+		make(protoReqHandlerMap),
+		make(textReqHandlerMap),
+		make(jsonMessageHandlerMap),
+		make(protoMessageHandlerMap),
+		make(streamJSONReqHandlerMap),
+		make(streamProtoReqHandlerMap),
+		make(streamHandlerMap),
+	}
+}
+
+// connConfig holds the state and configuration that a Handler or Client shares
+// with all of its Conns, other than the handlers.
+type connConfig struct {
+	topics        *topicRegistry
+	subscriptions *topicSubscriptions
+	middlewares   *middlewareChain
+	limiter       *limiter
+	rateLimits    *rateLimits
+	heartbeat     *heartbeatConfig
+	shutdown      *shutdownState
+	onGoingAway   func(*Conn)
+	metrics       *metricsConfig
+	tracer        *tracerConfig
+}
+
+func newConnConfig() connConfig {
+	return connConfig{
+		newTopicRegistry(),
+		newTopicSubscriptions(),
+		newMiddlewareChain(),
+		newLimiter(),
+		newRateLimits(),
+		newHeartbeatConfig(),
+		newShutdownState(),
+		nil,
+		newMetricsConfig(),
+		newTracerConfig(),
+	}
 }
 
 type request interface {
@@ -68,52 +166,125 @@ type response interface {
 // Internal - Outgoing wrappers
 ///////////////////////////////
 
-func (c *Conn) sendRequestAndWaitForResponse(reqID reqID, wireReq *wire.Request, resValPtr interface{}) (err error) {
-	c.resChans[reqID] = make(resChan)
-	defer delete(c.resChans, reqID)
-	defer func() { err = errs.Wrap(err, nil) }()
+// sendRequestAndWaitForResponse sends wireReq and waits for its response. sent, if not nil, gets
+// called once the request has been written, so that the next queued request may be sent.
+func (c *Conn) sendRequestAndWaitForResponse(ctx context.Context, reqID reqID, wireReq *wire.Request, resValPtr interface{}, sent func()) (resAttachments []Attachment, err error) {
+	defer func() {
+		// Context errors, ErrConnClosed and ResponseErrors are returned as is, so that callers can inspect them
+		var resErr *ResponseError
+		if !errors.As(err, &resErr) && err != ctx.Err() && err != ErrConnClosed {
+			err = errs.Wrap(err, nil)
+		}
+	}()
+
+	if err = setReqContext(ctx, wireReq); err != nil {
+		return
+	}
+	endSpan := c.startClientSpan(ctx, wireReq.Name, &wireReq.Traceparent)
+	defer func() { endSpan(err) }()
+
+	resChan, err := c.pending.add(reqID)
+	if err != nil {
+		return
+	}
+	defer c.pending.remove(reqID)
 
 	c.Log("REQ", wireReq.Name, "ReqID:", reqID, "len:", len(wireReq.Data))
 	err = c.sendWrapper(&wire.Wrapper{
 		Content: &wire.Wrapper_Request{Request: wireReq},
 	})
+	if sent != nil {
+		sent()
+	}
 	if err != nil {
 		return
 	}
 
-	wireRes := <-c.resChans[reqID]
+	var wireRes *wire.Response
+	var ok bool
+	select {
+	case wireRes, ok = <-resChan:
+		if !ok {
+			return nil, ErrConnClosed
+		}
+	case <-ctx.Done():
+		c.Log("CANCEL", wireReq.Name, "ReqID:", reqID, ctx.Err())
+		c.sendCancel(reqID)
+		return nil, ctx.Err()
+	}
 	c.Log("RCV", wireReq.Name, "ReqID:", reqID, "DataType:", wireRes.Type, "len(Data):", len(wireRes.Data))
 
 	if wireRes.IsError {
-		return errors.New(string(wireRes.Data))
+		return nil, newResponseError(wireRes.Error, wireRes.Data)
 	}
+	return fromWireAttachments(wireRes.Attachments), decodeWireData(wireRes.Type, wireRes.Data, resValPtr)
+}
 
-	if wireRes.Data == nil {
+// setReqContext sends the context's deadline, metadata and trace context along with wireReq.
+func setReqContext(ctx context.Context, wireReq *wire.Request) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	wireReq.Metadata = toWireMetadata(MetadataFromContext(ctx))
+	wireReq.Traceparent = traceparentFromContext(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		wireReq.TimeoutMs = int64(time.Until(deadline) / time.Millisecond)
+		if wireReq.TimeoutMs <= 0 {
+			return context.DeadlineExceeded
+		}
+	}
+	return nil
+}
+func (c *Conn) sendCancel(reqID reqID) error {
+	return c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_Cancel{Cancel: &wire.Cancel{ReqId: uint32(reqID)}},
+	})
+}
+func decodeWireData(dataType wire.DataType, data []byte, resValPtr interface{}) (err error) {
+	if data == nil && dataType != wire.DataType_Text {
 		return nil
 	}
 
-	switch wireRes.Type {
+	switch dataType {
+	case wire.DataType_Text:
+		if resValPtr == nil {
+			return nil
+		}
+		textPtr, ok := resValPtr.(*string)
+		if !ok {
+			err = errs.New(errs.Info{"data": string(data)}, "Expected string pointer to read text data into")
+			return
+		}
+		*textPtr = string(data)
+		return nil
 	case wire.DataType_JSON:
 		if resValPtr == nil {
-			err = errs.New(errs.Info{"data": string(wireRes.Data)}, "Expected struct pointer to deserialize JSON data into")
+			err = errs.New(errs.Info{"data": string(data)}, "Expected struct pointer to deserialize JSON data into")
 			return
 		}
-		return json.Unmarshal(wireRes.Data, resValPtr)
+		return json.Unmarshal(data, resValPtr)
 	case wire.DataType_Proto:
 		if resValPtr == nil {
-			err = errs.New(errs.Info{"len": len(wireRes.Data)}, "Expected struct pointer to deserialize protobuf data into")
+			err = errs.New(errs.Info{"len": len(data)}, "Expected struct pointer to deserialize protobuf data into")
 			return
 		}
-		return proto.Unmarshal(wireRes.Data, resValPtr.(proto.Message))
+		return proto.Unmarshal(data, resValPtr.(proto.Message))
 	default:
-		return errors.New("Bad response wire type: " + wireRes.Type.String())
+		return errors.New("Bad response wire type: " + dataType.String())
 	}
 }
-func (c *Conn) sendResponse(wireReq *wire.Request, response response) {
-	wireRes := &wire.Response{ReqId: wireReq.ReqId}
+func (c *Conn) sendMessage(wireMsg *wire.Message) error {
+	c.Log("MSG", wireMsg.Name, "len:", len(wireMsg.Data))
+	return errs.Wrap(c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_Message{Message: wireMsg},
+	}), nil)
+}
+func (c *Conn) sendResponse(wireReq *wire.Request, response response, attachments []Attachment) {
+	wireRes := &wire.Response{ReqId: wireReq.ReqId, Attachments: toWireAttachments(attachments)}
 	data, err := response.encode()
 	if err != nil {
-		panic(errs.Wrap(err, nil, "Unable to encode response"))
+		c.sendErrorResponse(wireReq, errs.Wrap(err, errs.Info{"Name": wireReq.Name}, "Unable to encode response"))
+		return
 	}
 	wireRes.Type = response.dataType()
 	wireRes.Data = data
@@ -121,27 +292,24 @@ func (c *Conn) sendResponse(wireReq *wire.Request, response response) {
 		Content: &wire.Wrapper_Response{Response: wireRes},
 	})
 	if err != nil {
-		panic(errs.Wrap(err, nil, "Unable to send response"))
+		c.Log("Unable to send response", wireReq.Name, err)
 	}
+	c.trackReqDone(wireReq, len(data), nil)
 }
 func (c *Conn) sendErrorResponse(wireReq *wire.Request, err error) {
-	var publicMessage string
-	if errsErr, ok := err.(errs.Err); ok {
-		publicMessage = errsErr.PublicMsg()
-	}
-	if publicMessage == "" {
-		publicMessage = DefaultPublicErrorMessage
-	}
+	wireErr := c.toWireError(err)
 	wireRes := &wire.Response{
 		ReqId:   wireReq.ReqId,
 		IsError: true,
 		Type:    wire.DataType_Text,
-		Data:    []byte(publicMessage),
+		Data:    []byte(wireErr.Message),
+		Error:   wireErr,
 	}
 	c.Log("Req ERROR", wireReq.ReqId, err)
 	c.sendWrapper(&wire.Wrapper{
 		Content: &wire.Wrapper_Response{Response: wireRes},
 	})
+	c.trackReqDone(wireReq, len(wireRes.Data), err)
 }
 func (c *Conn) nextReqID() reqID {
 	rawReqID := atomic.AddUint32((*uint32)(&c.lastReqID), 1)
@@ -153,6 +321,10 @@ func (c *Conn) sendWrapper(wrapper *wire.Wrapper) (err error) {
 		return
 	}
 	c.Log("SND Wrapper len:", len(wireData), wrapper)
+	return c.sendWrapperData(wireData)
+}
+func (c *Conn) sendWrapperData(wireData []byte) error {
+	c.liveness.sent()
 	return c.wsConn.SendBinary(wireData)
 }
 
@@ -178,6 +350,12 @@ func (c *Conn) readAndHandleWireWrapper(data []byte) {
 	}
 
 	c.Log("readAndHandleWireWrapper", wireWrapper.Content)
+	switch wireWrapper.Content.(type) {
+	case *wire.Wrapper_Ping, *wire.Wrapper_Pong:
+		c.liveness.received(false)
+	default:
+		c.liveness.received(true)
+	}
 	switch content := wireWrapper.Content.(type) {
 	case *wire.Wrapper_Message:
 		c.handleMessage(content.Message)
@@ -185,25 +363,131 @@ func (c *Conn) readAndHandleWireWrapper(data []byte) {
 		c.handleRequest(content.Request)
 	case *wire.Wrapper_Response:
 		c.handleResponse(content.Response)
+	case *wire.Wrapper_Cancel:
+		c.handleCancel(content.Cancel)
+	case *wire.Wrapper_StreamChunk:
+		c.handleStreamChunk(content.StreamChunk)
+	case *wire.Wrapper_StreamEnd:
+		c.handleStreamEnd(content.StreamEnd)
+	case *wire.Wrapper_StreamCredit:
+		c.handleStreamCredit(content.StreamCredit)
+	case *wire.Wrapper_StreamDataCredit:
+		c.handleStreamDataCredit(content.StreamDataCredit)
+	case *wire.Wrapper_StreamCancel:
+		c.handleStreamCancel(content.StreamCancel)
+	case *wire.Wrapper_StreamOpen:
+		c.handleStreamOpen(content.StreamOpen)
+	case *wire.Wrapper_StreamData:
+		c.handleStreamData(content.StreamData)
+	case *wire.Wrapper_StreamClose:
+		c.handleStreamClose(content.StreamClose)
+	case *wire.Wrapper_Subscribe:
+		c.handleSubscribe(content.Subscribe)
+	case *wire.Wrapper_Unsubscribe:
+		c.handleUnsubscribe(content.Unsubscribe)
+	case *wire.Wrapper_Publication:
+		c.handlePublication(content.Publication)
+	case *wire.Wrapper_Ping:
+		c.handlePing(content.Ping)
+	case *wire.Wrapper_Pong:
+		// Receiving the pong is all it takes to keep the connection alive
+	case *wire.Wrapper_GoingAway:
+		c.handleGoingAway(content.GoingAway)
 	default:
 		panic(errs.New(errs.Info{"Wrapper": wireWrapper}, "Unknown wire wrapper content type"))
 	}
 }
-func (c *Conn) handleMessage(msg *wire.Message) {
-	panic(errs.New(nil, "TODO: handleMessage"))
+func (c *Conn) handleMessage(wireMsg *wire.Message) {
+	c.Log("HANDLE MSG", wireMsg)
+	// Messages are handled in the order they arrive, on the connection's
+	// read loop. Handlers that need to block should spawn a goroutine.
+	defer func() {
+		if r := recover(); r != nil {
+			stack := string(runtimeDebug.Stack())
+			c.Log("Error while handling message", wireMsg.Name, r, stack)
+		}
+	}()
+	switch wireMsg.Type {
+	case wire.DataType_JSON:
+		c.handleJSONWireMessage(wireMsg)
+	case wire.DataType_Proto:
+		c.handleProtoWireMessage(wireMsg)
+	default:
+		c.Log("Bad wireMsg.Type", wireMsg.Type)
+	}
 }
 func (c *Conn) handleRequest(wireReq *wire.Request) {
 	c.Log("HANDLE REQ", wireReq)
+	c.trackReqStart(wireReq)
+	if wireReq.Stream {
+		c.handleStreamRequest(wireReq)
+		return
+	}
 	switch wireReq.Type {
 	case wire.DataType_JSON:
-		go c.handleJSONWireReq(wireReq)
+		c.startHandler(wireReq, c.handleJSONWireReq, c.sendErrorResponse)
 	case wire.DataType_Proto:
-		go c.handleProtoWireReq(wireReq)
+		c.startHandler(wireReq, c.handleProtoWireReq, c.sendErrorResponse)
+	case wire.DataType_Text:
+		c.startHandler(wireReq, c.handleTextWireReq, c.sendErrorResponse)
 	default:
 		c.sendErrorResponse(wireReq, errs.New(errs.Info{"Type": wireReq.Type}, "Bad wireReq.Type"))
 	}
 }
 func (c *Conn) handleResponse(wireRes *wire.Response) {
 	c.Log("HANDLE RES", wireRes)
-	c.resChans[reqID(wireRes.ReqId)] <- wireRes
+	if !c.pending.resolve(wireRes) {
+		// Stray or duplicate response, or the request was cancelled before the response arrived
+		c.Log("Dropping response for unknown request", wireRes.ReqId)
+	}
+}
+func (c *Conn) handleCancel(wireCancel *wire.Cancel) {
+	c.Log("HANDLE CANCEL", wireCancel)
+	c.cancelsMutex.Lock()
+	defer c.cancelsMutex.Unlock()
+	if cancel, exists := c.cancels[reqID(wireCancel.ReqId)]; exists {
+		cancel()
+	}
+}
+
+// close fails all pending outgoing requests and streams with ErrConnClosed,
+// and cancels the contexts of all incoming requests.
+func (c *Conn) close() {
+	c.liveness.stopHeartbeat()
+	c.pending.close()
+	c.streams.close()
+	c.openedStreams.close()
+	c.acceptedStreams.close()
+	c.liveStreams.close()
+	c.cancelsMutex.Lock()
+	defer c.cancelsMutex.Unlock()
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+}
+
+// startReqContext returns the context for an incoming request, which carries
+// the request metadata and trace context, and gets cancelled when the requester cancels or when
+// its deadline passes. Call done once the request has been handled.
+func (c *Conn) startReqContext(wireReq *wire.Request) (ctx context.Context, done func()) {
+	var cancel context.CancelFunc
+	if wireReq.TimeoutMs > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(wireReq.TimeoutMs)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	if md := fromWireMetadata(wireReq.Metadata); md != nil {
+		ctx = ContextWithMetadata(ctx, md)
+	}
+	ctx = withTrace(ctx, wireReq.Traceparent, c.trackedSpan(wireReq))
+	id := reqID(wireReq.ReqId)
+	c.cancelsMutex.Lock()
+	c.cancels[id] = cancel
+	c.cancelsMutex.Unlock()
+	return ctx, func() {
+		c.cancelsMutex.Lock()
+		delete(c.cancels, id)
+		c.cancelsMutex.Unlock()
+		cancel()
+	}
 }
@@ -1,6 +1,14 @@
 package birect
 
-import "github.com/marcuswestin/go-errs"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
 
 var (
 	// NewError creates an error with debugging information, such as stack traces, etc.
@@ -11,4 +19,130 @@ var (
 
 	// DefaultPublicErrorMessage will be set as the public error message for any error without one.
 	DefaultPublicErrorMessage = "Oops! Something went wrong - please try again."
+
+	// ErrConnClosed is returned for requests that are pending, or get sent, after their Conn has closed.
+	ErrConnClosed = errors.New("birect: connection closed")
+
+	// ErrOfflineQueueFull is returned for requests sent while a Client is reconnecting, once its offline queue is full.
+	ErrOfflineQueueFull = errors.New("birect: offline queue is full")
+
+	// ErrRequestExpired is returned for requests that spent longer than OfflineQueue.MaxAge in a Client's offline queue.
+	ErrRequestExpired = errors.New("birect: request expired in offline queue")
+
+	// ErrStreamOverflow is returned by the Recv of streams whose sender sent more than the reader
+	// had room for. A Conn never waits for slow stream readers, since that would hold up everything
+	// else on the Conn.
+	ErrStreamOverflow = errors.New("birect: stream overflowed its buffer")
 )
+
+// ResponseError is a structured error that handlers can return to give requesters
+// a machine-readable error code, retry hints and details. Requests that fail with an
+// error response always return a *ResponseError, e.g
+//
+//	err := conn.SendJSONReq("Upload", &res, params)
+//	var resErr *birect.ResponseError
+//	if errors.As(err, &resErr) && resErr.Retryable {
+//		time.Sleep(resErr.RetryAfter)
+//	}
+//
+// Errors other than ResponseErrors are sent with their errs public message, or
+// DefaultPublicErrorMessage, as Message.
+type ResponseError struct {
+	Message    string
+	Code       string
+	Retryable  bool
+	RetryAfter time.Duration
+	// Details is sent as proto if it implements Proto, and as JSON otherwise.
+	// On the receiving side Details is nil - use ParseDetails instead.
+	Details     interface{}
+	detailsType wire.DataType
+	detailsData []byte
+}
+
+// Error returns the public error message
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// HasDetails returns true if the error response came with details.
+func (e *ResponseError) HasDetails() bool {
+	return len(e.detailsData) > 0
+}
+
+// ParseDetails parses the received error details into valuePtr. valuePtr should be
+// a JSON-parsable pointer for JSON details, and a Proto for proto details.
+func (e *ResponseError) ParseDetails(valuePtr interface{}) error {
+	return decodeWireData(e.detailsType, e.detailsData, valuePtr)
+}
+
+// Internal
+///////////
+
+// wrapHandlerError adds debugging information to handler errors. Errors that are
+// or wrap ResponseErrors are left as is, so that they get sent as given.
+func wrapHandlerError(err error, info errs.Info) error {
+	var resErr *ResponseError
+	if errors.As(err, &resErr) {
+		return err
+	}
+	return errs.Wrap(err, info)
+}
+
+func publicErrorMessage(err error) (publicMessage string) {
+	if errsErr, ok := err.(errs.Err); ok {
+		publicMessage = errsErr.PublicMsg()
+	}
+	if publicMessage == "" {
+		publicMessage = DefaultPublicErrorMessage
+	}
+	return
+}
+
+func (c *Conn) toWireError(err error) *wire.Error {
+	var resErr *ResponseError
+	if !errors.As(err, &resErr) {
+		return &wire.Error{Message: publicErrorMessage(err)}
+	}
+	wireErr := &wire.Error{
+		Message:      resErr.Message,
+		Code:         resErr.Code,
+		Retryable:    resErr.Retryable,
+		RetryAfterMs: int64(resErr.RetryAfter / time.Millisecond),
+	}
+	if wireErr.Message == "" {
+		wireErr.Message = DefaultPublicErrorMessage
+	}
+	if resErr.Details != nil {
+		var details []byte
+		var detailsErr error
+		if protoDetails, isProto := resErr.Details.(Proto); isProto {
+			wireErr.DetailsType = wire.DataType_Proto
+			details, detailsErr = proto.Marshal(protoDetails)
+		} else {
+			wireErr.DetailsType = wire.DataType_JSON
+			details, detailsErr = json.Marshal(resErr.Details)
+		}
+		if detailsErr != nil {
+			c.Log("Unable to encode error details", detailsErr)
+		} else {
+			wireErr.Details = details
+		}
+	}
+	return wireErr
+}
+
+// newResponseError creates the error for an error response. Peers that don't send
+// structured errors only send the public message as text data.
+func newResponseError(wireErr *wire.Error, data []byte) *ResponseError {
+	if wireErr == nil {
+		return &ResponseError{Message: string(data)}
+	}
+	return &ResponseError{
+		Message:     wireErr.Message,
+		Code:        wireErr.Code,
+		Retryable:   wireErr.Retryable,
+		RetryAfter:  time.Duration(wireErr.RetryAfterMs) * time.Millisecond,
+		detailsType: wireErr.DetailsType,
+		detailsData: wireErr.Details,
+	}
+}
@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package birect
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/marcuswestin/go-errs"
+)
+
+// JSONReqHandlerRegistry is implemented by Handler and Client, for use with HandleJSON.
+type JSONReqHandlerRegistry interface {
+	HandleJSONReq(reqName string, handler JSONReqHandler)
+}
+
+// JSONReqSender is implemented by Conn and Client, for use with CallJSON.
+type JSONReqSender interface {
+	SendJSONReqContext(ctx context.Context, name string, resValPtr interface{}, paramsObj interface{}, attachments ...Attachment) error
+}
+
+// HandleJSON registers a typed JSON request handler with the given `name`. Params get
+// parsed into a P, and the returned R gets sent as the response, e.g
+//
+//	birect.HandleJSON(server, "Echo", func(ctx context.Context, conn *birect.Conn, par EchoParams) (EchoResponse, error) {
+//		return EchoResponse{par.Text}, nil
+//	})
+//
+// Params that fail to parse into a P get an error response, without calling handler.
+func HandleJSON[P, R any](registry JSONReqHandlerRegistry, name string, handler func(ctx context.Context, conn *Conn, params P) (R, error)) {
+	registry.HandleJSONReq(name, func(req *JSONReq) (interface{}, error) {
+		var params P
+		if len(req.data) > 0 {
+			if err := json.Unmarshal(req.data, &params); err != nil {
+				return nil, errs.Wrap(err, errs.Info{"Name": name}, "Unable to parse params")
+			}
+		}
+		return handler(req.Context(), req.Conn, params)
+	})
+}
+
+// CallJSON sends a JSON request with the given `name` and params, and returns the response parsed into an R.
+func CallJSON[P, R any](ctx context.Context, sender JSONReqSender, name string, params P) (res R, err error) {
+	err = sender.SendJSONReqContext(ctx, name, &res, params)
+	return
+}
@@ -0,0 +1,189 @@
+package birect
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// Heartbeat configures liveness detection and idle timeouts for connections.
+//
+// Every Interval, a ping gets sent to the other side, which answers with a pong. A connection
+// from which nothing at all has been received for Timeout is considered dead, and gets closed.
+// Timeout defaults to three times Interval. A zero Interval disables pings.
+//
+// A connection over which no messages, requests, responses or stream data have been sent or
+// received for IdleTimeout gets closed as well. Pings and pongs do not count as activity, while
+// requests that are being handled or waiting for their response, and open streams, always do.
+// A zero IdleTimeout disables the idle timeout.
+//
+// Closed connections fire the Handler's DisconnectHandler, and make a Client reconnect.
+type Heartbeat struct {
+	Interval    time.Duration
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+}
+
+// SetHeartbeat sets the heartbeat of the handler's connections. The heartbeat
+// applies to connections that connect after SetHeartbeat gets called.
+func (s *Handler) SetHeartbeat(heartbeat Heartbeat) {
+	s.heartbeat.set(heartbeat)
+}
+
+// SetHeartbeat sets the heartbeat of the client's connection, including
+// the connections it makes when reconnecting.
+func (client *Client) SetHeartbeat(heartbeat Heartbeat) {
+	client.heartbeat.set(heartbeat)
+	client.CurrentConn().startHeartbeat(heartbeat)
+}
+
+// Internal
+///////////
+
+// heartbeatConfig holds the Heartbeat of a Handler or Client.
+type heartbeatConfig struct {
+	mutex     *sync.Mutex
+	heartbeat Heartbeat
+}
+
+func newHeartbeatConfig() *heartbeatConfig {
+	return &heartbeatConfig{&sync.Mutex{}, Heartbeat{}}
+}
+
+func (h *heartbeatConfig) set(heartbeat Heartbeat) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.heartbeat = heartbeat
+}
+
+func (h *heartbeatConfig) get() Heartbeat {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.heartbeat
+}
+
+// liveness tracks when a Conn last received anything, and when it last had any activity.
+type liveness struct {
+	lastReceived int64 // UnixNano, accessed atomically
+	lastActive   int64 // UnixNano, accessed atomically
+	mutex        *sync.Mutex
+	stop         chan struct{} // Closed to stop the running heartbeat loop, if any
+}
+
+func newLiveness() *liveness {
+	now := time.Now().UnixNano()
+	return &liveness{now, now, &sync.Mutex{}, nil}
+}
+
+func (l *liveness) received(active bool) {
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&l.lastReceived, now)
+	if active {
+		atomic.StoreInt64(&l.lastActive, now)
+	}
+}
+
+func (l *liveness) sent() {
+	atomic.StoreInt64(&l.lastActive, time.Now().UnixNano())
+}
+
+func (l *liveness) sinceReceived(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&l.lastReceived)))
+}
+
+func (l *liveness) sinceActive(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, atomic.LoadInt64(&l.lastActive)))
+}
+
+// restart stops the running heartbeat loop, if any, and returns the stop channel for a new one.
+func (l *liveness) restart() chan struct{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.stop != nil {
+		close(l.stop)
+	}
+	l.stop = make(chan struct{})
+	return l.stop
+}
+
+func (l *liveness) stopHeartbeat() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+}
+
+// startHeartbeat starts sending pings and checking the connection's liveness
+// in the background, replacing any previously started heartbeat.
+func (c *Conn) startHeartbeat(heartbeat Heartbeat) {
+	if heartbeat.Interval <= 0 && heartbeat.IdleTimeout <= 0 {
+		c.liveness.stopHeartbeat()
+		return
+	}
+	if heartbeat.Interval > 0 && heartbeat.Timeout <= 0 {
+		heartbeat.Timeout = 3 * heartbeat.Interval
+	}
+	go c.runHeartbeat(heartbeat, c.liveness.restart())
+}
+
+func (c *Conn) runHeartbeat(heartbeat Heartbeat, stop chan struct{}) {
+	// Check often enough to notice an idle connection within a quarter of IdleTimeout
+	checkInterval := heartbeat.Interval
+	if heartbeat.IdleTimeout > 0 && (checkInterval <= 0 || heartbeat.IdleTimeout/4 < checkInterval) {
+		checkInterval = heartbeat.IdleTimeout / 4
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	lastPing := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if heartbeat.Interval > 0 && c.liveness.sinceReceived(now) > heartbeat.Timeout {
+				c.Log("Heartbeat timeout", "Timeout:", heartbeat.Timeout)
+				c.Close()
+				return
+			}
+			if heartbeat.IdleTimeout > 0 && c.liveness.sinceActive(now) > heartbeat.IdleTimeout && !c.busy() {
+				c.Log("Idle timeout", "IdleTimeout:", heartbeat.IdleTimeout)
+				c.Close()
+				return
+			}
+			// Allow for ticker jitter when the check interval equals the ping interval
+			if heartbeat.Interval > 0 && now.Sub(lastPing)+checkInterval/2 >= heartbeat.Interval {
+				lastPing = now
+				c.sendHeartbeatWrapper(&wire.Wrapper{Content: &wire.Wrapper_Ping{Ping: &wire.Ping{}}})
+			}
+		}
+	}
+}
+
+// busy returns true if the Conn is handling requests or streams, waiting for responses,
+// or has open streams. Busy connections are never idle.
+func (c *Conn) busy() bool {
+	c.cancelsMutex.Lock()
+	handling := len(c.cancels)
+	c.cancelsMutex.Unlock()
+	return handling > 0 || c.pending.len() > 0 || c.streams.len() > 0 || c.liveStreams.len() > 0
+}
+
+func (c *Conn) handlePing(wirePing *wire.Ping) {
+	c.sendHeartbeatWrapper(&wire.Wrapper{Content: &wire.Wrapper_Pong{Pong: &wire.Pong{}}})
+}
+
+// sendHeartbeatWrapper sends pings and pongs, which do not count as activity.
+func (c *Conn) sendHeartbeatWrapper(wrapper *wire.Wrapper) {
+	wireData, err := proto.Marshal(wrapper)
+	if err == nil {
+		err = c.wsConn.SendBinary(wireData)
+	}
+	if err != nil {
+		c.Log("Unable to send heartbeat", err)
+	}
+}
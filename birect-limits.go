@@ -0,0 +1,204 @@
+package birect
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// BusyCode is the ResponseError.Code of requests that get rejected because a Handler's Limits are reached.
+const BusyCode = "BUSY"
+
+// Limits configures how many request and stream handlers run at once. Requests that arrive while a
+// limit is reached wait for a running handler to finish, up to QueueSize requests per
+// connection. Beyond that, requests get rejected with a retryable ResponseError with
+// Code BusyCode. A zero MaxInFlight or MaxInFlightPerConn means no limit.
+type Limits struct {
+	MaxInFlight        int
+	MaxInFlightPerConn int
+	QueueSize          int
+}
+
+// SetLimits sets the limits for request handlers. Limits apply to connections
+// that connect after SetLimits gets called.
+func (s *Handler) SetLimits(limits Limits) {
+	s.limiter.set(limits)
+}
+
+// Internal
+///////////
+
+// limiter holds a Handler's Limits, along with the global handler slots.
+type limiter struct {
+	mutex  *sync.Mutex
+	limits Limits
+	global chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{&sync.Mutex{}, Limits{}, nil}
+}
+
+func (l *limiter) set(limits Limits) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limits = limits
+	l.global = nil
+	if limits.MaxInFlight > 0 {
+		l.global = make(chan struct{}, limits.MaxInFlight)
+	}
+}
+
+func (l *limiter) newReqSlots() *reqSlots {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	slots := &reqSlots{&sync.Mutex{}, nil, l.global, l.limits.QueueSize, 0}
+	if l.limits.MaxInFlightPerConn > 0 {
+		slots.conn = make(chan struct{}, l.limits.MaxInFlightPerConn)
+	}
+	return slots
+}
+
+// reqSlots limits the number of a Conn's running request handlers. Nil channels mean no limit.
+type reqSlots struct {
+	mutex     *sync.Mutex
+	conn      chan struct{}
+	global    chan struct{}
+	queueSize int
+	queued    int
+}
+
+// tryAcquire takes a handler slot if one is free.
+func (s *reqSlots) tryAcquire() bool {
+	if s.conn != nil {
+		select {
+		case s.conn <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	if s.global != nil {
+		select {
+		case s.global <- struct{}{}:
+		default:
+			if s.conn != nil {
+				<-s.conn
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// acquire waits for a handler slot. It returns false if ctx is done first.
+func (s *reqSlots) acquire(ctx context.Context) bool {
+	if s.conn != nil {
+		select {
+		case s.conn <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	if s.global != nil {
+		select {
+		case s.global <- struct{}{}:
+		case <-ctx.Done():
+			if s.conn != nil {
+				<-s.conn
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func (s *reqSlots) release() {
+	if s.global != nil {
+		<-s.global
+	}
+	if s.conn != nil {
+		<-s.conn
+	}
+}
+
+// enqueue reserves a place in the queue of requests that wait for a slot.
+func (s *reqSlots) enqueue() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.queued >= s.queueSize {
+		return false
+	}
+	s.queued++
+	return true
+}
+
+func (s *reqSlots) dequeue() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queued--
+}
+
+// startHandler calls handle in a new goroutine once a handler slot is free. If the
+// request can not wait for a slot it gets rejected with a busy error, sent with reject.
+func (c *Conn) startHandler(wireReq *wire.Request, handle func(ctx context.Context, wireReq *wire.Request), reject func(wireReq *wire.Request, err error)) {
+	ctx, done := c.startReqContext(wireReq)
+	c.runInSlot(ctx, wireReq.Name, func() {
+		defer done()
+		handle(ctx, wireReq)
+	}, func(err error) {
+		done()
+		reject(wireReq, err)
+	}, func() {
+		// The request was cancelled, or its deadline passed, while waiting
+		done()
+		c.trackReqDone(wireReq, 0, ctx.Err())
+	})
+}
+
+// runInSlot calls run in a new goroutine once a handler slot is free, and frees the slot
+// once run returns. If the Handler is shutting down, the request is over a rate limit, or
+// there is no room to wait for a slot, runInSlot calls reject with a shutting down, rate
+// limited or busy error instead. If ctx is done while waiting, it calls cancelled.
+func (c *Conn) runInSlot(ctx context.Context, name string, run func(), reject func(err error), cancelled func()) {
+	if !c.shutdown.startReq() {
+		c.Log("Rejecting request - shutting down", name)
+		reject(newShuttingDownError())
+		return
+	}
+	// The request is in flight for Shutdown until it has run, been rejected or been cancelled.
+	// Rate limits come first, so that requests over the limit never take a slot or a place in the queue
+	refund, err := c.rateLimits.take(&HandlerReq{name, c, ctx})
+	if err != nil {
+		c.Log("Rejecting request - rate limited", name)
+		reject(err)
+		c.shutdown.doneReq()
+		return
+	}
+	runAndRelease := func() {
+		defer c.shutdown.doneReq()
+		defer c.slots.release()
+		run()
+	}
+	if c.slots.tryAcquire() {
+		go runAndRelease()
+		return
+	}
+	if !c.slots.enqueue() {
+		c.Log("Rejecting request - too many requests in flight", name)
+		refund()
+		reject(&ResponseError{Message: "Server is busy - please try again.", Code: BusyCode, Retryable: true})
+		c.shutdown.doneReq()
+		return
+	}
+	go func() {
+		acquired := c.slots.acquire(ctx)
+		c.slots.dequeue()
+		if !acquired {
+			cancelled()
+			c.shutdown.doneReq()
+			return
+		}
+		runAndRelease()
+	}()
+}
@@ -0,0 +1,65 @@
+package birect
+
+import (
+	"context"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// Metadata holds key-value pairs that are sent along with a request, such as auth tokens
+// or trace ids. Attach metadata to outgoing requests with ContextWithMetadata, and read
+// it in handlers with MetadataFromContext(req.Context()).
+type Metadata map[string]string
+
+// ContextWithMetadata returns a copy of ctx that carries md. Requests sent
+// with the returned context send md along with the request.
+func ContextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext returns the metadata carried by ctx, or nil if there is none.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// Get returns the value of the given key, or "" if it is not set. Get may be called on nil Metadata.
+func (md Metadata) Get(key string) string {
+	return md[key]
+}
+
+// Copy returns a copy of md that can be modified without affecting md.
+func (md Metadata) Copy() Metadata {
+	copied := make(Metadata, len(md))
+	for key, value := range md {
+		copied[key] = value
+	}
+	return copied
+}
+
+// Internal
+///////////
+
+type metadataKey struct{}
+
+func toWireMetadata(md Metadata) []*wire.MetadataPair {
+	if len(md) == 0 {
+		return nil
+	}
+	wirePairs := make([]*wire.MetadataPair, 0, len(md))
+	for key, value := range md {
+		wirePairs = append(wirePairs, &wire.MetadataPair{Key: key, Value: value})
+	}
+	return wirePairs
+}
+
+func fromWireMetadata(wirePairs []*wire.MetadataPair) Metadata {
+	if len(wirePairs) == 0 {
+		return nil
+	}
+	md := make(Metadata, len(wirePairs))
+	for _, pair := range wirePairs {
+		md[pair.Key] = pair.Value
+	}
+	return md
+}
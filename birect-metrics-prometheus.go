@@ -0,0 +1,204 @@
+package birect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics is a MetricsCollector that keeps counters and histograms in memory. Metrics is
+// also an http.Handler, which serves the metrics in the Prometheus text format:
+//
+//	metrics := birect.NewMetrics()
+//	server.SetMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+type Metrics struct {
+	mutex       *sync.Mutex
+	conns       int
+	connects    uint64
+	disconnects uint64
+	reqs        map[reqLabels]*reqStats
+}
+
+// NewMetrics returns a new, empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{&sync.Mutex{}, 0, 0, 0, make(map[reqLabels]*reqStats)}
+}
+
+// ConnOpened counts a new connection.
+func (m *Metrics) ConnOpened(conn *Conn) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.conns++
+	m.connects++
+}
+
+// ConnClosed counts a closed connection.
+func (m *Metrics) ConnClosed(conn *Conn) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.conns--
+	m.disconnects++
+}
+
+// RequestDone counts a handled request, and observes its duration and sizes.
+func (m *Metrics) RequestDone(req RequestMetrics) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	labels := reqLabels{req.Name, req.Type}
+	stats, exists := m.reqs[labels]
+	if !exists {
+		stats = newReqStats()
+		m.reqs[labels] = stats
+	}
+	stats.count++
+	if req.Err != nil {
+		code := ""
+		var resErr *ResponseError
+		if errors.As(req.Err, &resErr) {
+			code = resErr.Code
+		}
+		stats.errors[code]++
+	}
+	stats.duration.observe(req.Duration.Seconds())
+	stats.reqSize.observe(float64(req.ReqSize))
+	stats.resSize.observe(float64(req.ResSize))
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	out := bufio.NewWriter(w)
+	m.write(out)
+	out.Flush()
+}
+
+// Internal
+///////////
+
+// Histogram buckets, the same as the Prometheus client's defaults for durations
+var (
+	durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	sizeBuckets     = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+)
+
+type reqLabels struct {
+	name     string
+	dataType string
+}
+
+func (l reqLabels) String() string {
+	return fmt.Sprintf(`name="%s",type="%s"`, escapeLabelValue(l.name), escapeLabelValue(l.dataType))
+}
+
+type reqStats struct {
+	count    uint64
+	errors   map[string]uint64 // By ResponseError.Code
+	duration *histogram
+	reqSize  *histogram
+	resSize  *histogram
+}
+
+func newReqStats() *reqStats {
+	return &reqStats{0, make(map[string]uint64), newHistogram(durationBuckets), newHistogram(sizeBuckets), newHistogram(sizeBuckets)}
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of observations <= buckets[i], but > buckets[i-1]
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets, make([]uint64, len(buckets)), 0, 0}
+}
+
+func (h *histogram) observe(value float64) {
+	h.sum += value
+	h.count++
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+}
+
+func (h *histogram) write(out *bufio.Writer, name string, labels string) {
+	var cumulative uint64
+	for i, bucket := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(out, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bucket), cumulative)
+	}
+	fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(out, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(out, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func (m *Metrics) write(out *bufio.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	writeHeader(out, "birect_connections", "gauge", "Number of current connections.")
+	fmt.Fprintf(out, "birect_connections %d\n", m.conns)
+	writeHeader(out, "birect_connects_total", "counter", "Number of connections opened.")
+	fmt.Fprintf(out, "birect_connects_total %d\n", m.connects)
+	writeHeader(out, "birect_disconnects_total", "counter", "Number of connections closed.")
+	fmt.Fprintf(out, "birect_disconnects_total %d\n", m.disconnects)
+
+	labels := make([]reqLabels, 0, len(m.reqs))
+	for l := range m.reqs {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].name != labels[j].name {
+			return labels[i].name < labels[j].name
+		}
+		return labels[i].dataType < labels[j].dataType
+	})
+
+	writeHeader(out, "birect_requests_total", "counter", "Number of handled requests.")
+	for _, l := range labels {
+		fmt.Fprintf(out, "birect_requests_total{%s} %d\n", l, m.reqs[l].count)
+	}
+	writeHeader(out, "birect_request_errors_total", "counter", "Number of requests that failed, by error code.")
+	for _, l := range labels {
+		errors := m.reqs[l].errors
+		codes := make([]string, 0, len(errors))
+		for code := range errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(out, "birect_request_errors_total{%s,code=\"%s\"} %d\n", l, escapeLabelValue(code), errors[code])
+		}
+	}
+	writeHeader(out, "birect_request_duration_seconds", "histogram", "Time from when requests arrived until their responses were sent.")
+	for _, l := range labels {
+		m.reqs[l].duration.write(out, "birect_request_duration_seconds", l.String())
+	}
+	writeHeader(out, "birect_request_size_bytes", "histogram", "Size of request data.")
+	for _, l := range labels {
+		m.reqs[l].reqSize.write(out, "birect_request_size_bytes", l.String())
+	}
+	writeHeader(out, "birect_response_size_bytes", "histogram", "Size of response data.")
+	for _, l := range labels {
+		m.reqs[l].resSize.write(out, "birect_response_size_bytes", l.String())
+	}
+}
+
+func writeHeader(out *bufio.Writer, name, metricType, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
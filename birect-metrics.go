@@ -0,0 +1,160 @@
+package birect
+
+import (
+	"sync"
+	"time"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// MetricsCollector receives metrics about a Handler's connections and requests. Its methods
+// get called concurrently from all connections, and should return quickly.
+type MetricsCollector interface {
+	ConnOpened(conn *Conn)
+	ConnClosed(conn *Conn)
+	RequestDone(req RequestMetrics)
+}
+
+// RequestMetrics describes a request that has been handled.
+type RequestMetrics struct {
+	Conn *Conn
+	// Name is "unknown" for requests without a handler, since requesters choose the names.
+	Name   string
+	Type   string // "JSON", "Proto" or "Text"
+	Stream bool
+	// Duration is the time from when the request arrived until its response was sent.
+	Duration time.Duration
+	// ReqSize and ResSize are the number of bytes of request and response data.
+	// ResSize of stream requests is the total size of all the chunks.
+	ReqSize int
+	ResSize int
+	// Err is the error that the request failed with, or nil if it succeeded.
+	Err error
+}
+
+// SetMetrics makes the handler report the metrics of its connections and requests to
+// collector. Call SetMetrics before the handler starts accepting connections; the closing of
+// connections that were opened before then is not reported. Use
+// NewMetrics for a collector that serves the metrics in the Prometheus text format.
+func (s *Handler) SetMetrics(collector MetricsCollector) {
+	s.metrics.set(collector)
+}
+
+// Internal
+///////////
+
+// metricsConfig holds the MetricsCollector of a Handler.
+type metricsConfig struct {
+	mutex     *sync.Mutex
+	collector MetricsCollector
+}
+
+func newMetricsConfig() *metricsConfig {
+	return &metricsConfig{&sync.Mutex{}, nil}
+}
+
+func (m *metricsConfig) set(collector MetricsCollector) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.collector = collector
+}
+
+func (m *metricsConfig) get() MetricsCollector {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.collector
+}
+
+// reqTracker tracks a Conn's requests from when they arrive until their responses are sent,
+// for metrics and tracing.
+type reqTracker struct {
+	mutex *sync.Mutex
+	reqs  map[reqID]*trackedReq
+}
+
+type trackedReq struct {
+	start   time.Time
+	resSize int
+	span    *Span // Set if the Conn has a Tracer
+	tracer  Tracer
+}
+
+func newReqTracker() *reqTracker {
+	return &reqTracker{&sync.Mutex{}, make(map[reqID]*trackedReq)}
+}
+
+// hasReqHandler returns true if there is a handler for wireReq's name and type.
+func (h handlerMaps) hasReqHandler(wireReq *wire.Request) (exists bool) {
+	switch {
+	case wireReq.Stream && wireReq.Type == wire.DataType_JSON:
+		_, exists = h.streamJSONReqHandlerMap[wireReq.Name]
+	case wireReq.Stream && wireReq.Type == wire.DataType_Proto:
+		_, exists = h.streamProtoReqHandlerMap[wireReq.Name]
+	case wireReq.Type == wire.DataType_JSON:
+		_, exists = h.jsonReqHandlerMap[wireReq.Name]
+	case wireReq.Type == wire.DataType_Proto:
+		_, exists = h.protoReqHandlerMap[wireReq.Name]
+	case wireReq.Type == wire.DataType_Text:
+		_, exists = h.textReqHandlerMap[wireReq.Name]
+	}
+	return
+}
+
+func (c *Conn) trackReqStart(wireReq *wire.Request) {
+	tracer := c.tracer.get()
+	if c.metrics.get() == nil && tracer == nil {
+		return
+	}
+	c.tracker.mutex.Lock()
+	defer c.tracker.mutex.Unlock()
+	c.tracker.reqs[reqID(wireReq.ReqId)] = &trackedReq{time.Now(), 0, startServerSpan(tracer, wireReq.Name, wireReq.Traceparent), tracer}
+}
+
+// trackedSpan returns the server span of wireReq, or nil if it has none.
+func (c *Conn) trackedSpan(wireReq *wire.Request) *Span {
+	c.tracker.mutex.Lock()
+	defer c.tracker.mutex.Unlock()
+	if req, exists := c.tracker.reqs[reqID(wireReq.ReqId)]; exists {
+		return req.span
+	}
+	return nil
+}
+
+func (c *Conn) trackReqData(wireReq *wire.Request, size int) {
+	c.tracker.mutex.Lock()
+	defer c.tracker.mutex.Unlock()
+	if req, exists := c.tracker.reqs[reqID(wireReq.ReqId)]; exists {
+		req.resSize += size
+	}
+}
+
+func (c *Conn) trackReqDone(wireReq *wire.Request, resSize int, err error) {
+	c.tracker.mutex.Lock()
+	req, exists := c.tracker.reqs[reqID(wireReq.ReqId)]
+	delete(c.tracker.reqs, reqID(wireReq.ReqId))
+	c.tracker.mutex.Unlock()
+	if !exists {
+		return
+	}
+	if req.span != nil {
+		exportSpan(req.tracer, req.span, err)
+	}
+	collector := c.metrics.get()
+	if collector == nil {
+		return
+	}
+	name := wireReq.Name
+	if !c.hasReqHandler(wireReq) {
+		name = "unknown"
+	}
+	collector.RequestDone(RequestMetrics{
+		Conn:     c,
+		Name:     name,
+		Type:     wireReq.Type.String(),
+		Stream:   wireReq.Stream,
+		Duration: time.Since(req.start),
+		ReqSize:  len(wireReq.Data),
+		ResSize:  req.resSize + resSize,
+		Err:      err,
+	})
+}
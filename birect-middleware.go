@@ -0,0 +1,113 @@
+package birect
+
+import (
+	"context"
+	"fmt"
+	runtimeDebug "runtime/debug"
+	"sync"
+
+	"github.com/marcuswestin/go-errs"
+)
+
+// Middleware wraps the handling of every JSON, proto and text request, as well as every
+// streaming request and bidirectional stream. Call next to continue to the next middleware,
+// and eventually to the request handler. Middleware may inspect or replace the result and
+// error, or return without calling next to short-circuit the request. The result of streaming
+// requests and streams is always nil. Panics in middleware and handlers are returned as errors.
+//
+//	server.Use(func(req *birect.HandlerReq, next birect.NextFunc) (interface{}, error) {
+//		if req.Info().GetString("UserID") == "" {
+//			return nil, &birect.ResponseError{Code: "UNAUTHENTICATED"}
+//		}
+//		return next()
+//	})
+type Middleware func(req *HandlerReq, next NextFunc) (res interface{}, err error)
+
+// NextFunc continues handling a request. See Middleware.
+type NextFunc func() (res interface{}, err error)
+
+// HandlerReq describes a request that is being handled, for Middleware.
+// Results are interface{} for JSON requests, Proto for proto requests
+// and string for text requests.
+type HandlerReq struct {
+	Name string
+	Conn *Conn
+	ctx  context.Context
+}
+
+// Context returns the request context. See JSONReq.Context.
+func (r *HandlerReq) Context() context.Context {
+	return r.ctx
+}
+
+// Metadata returns the metadata that was sent along with the request.
+func (r *HandlerReq) Metadata() Metadata {
+	return MetadataFromContext(r.ctx)
+}
+
+// Info returns the Info of the request's Conn.
+func (r *HandlerReq) Info() Info {
+	return r.Conn.Info
+}
+
+// Use adds middleware to wrap all request handlers with. Middleware runs in
+// the order it was added, i.e the first middleware wraps all the others.
+func (s *Handler) Use(middleware ...Middleware) {
+	s.middlewares.add(middleware)
+}
+
+// Internal
+///////////
+
+// middlewareChain is shared between a Handler and all of its Conns. It is safe for concurrent use.
+type middlewareChain struct {
+	mutex       *sync.Mutex
+	middlewares []Middleware
+}
+
+func newMiddlewareChain() *middlewareChain {
+	return &middlewareChain{&sync.Mutex{}, nil}
+}
+
+func (m *middlewareChain) add(middlewares []Middleware) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.middlewares = append(m.middlewares[:len(m.middlewares):len(m.middlewares)], middlewares...)
+}
+
+func (m *middlewareChain) get() []Middleware {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.middlewares
+}
+
+// runReqHandler runs handler through the middleware chain.
+func (c *Conn) runReqHandler(ctx context.Context, name string, handler NextFunc) (res interface{}, err error) {
+	defer c.recoverHandlerPanic(name, &err)
+	req := &HandlerReq{name, c, ctx}
+	middlewares := c.middlewares.get()
+	var next func(i int) NextFunc
+	next = func(i int) NextFunc {
+		if i == len(middlewares) {
+			return handler
+		}
+		return func() (res interface{}, err error) {
+			defer c.recoverHandlerPanic(name, &err)
+			return middlewares[i](req, next(i+1))
+		}
+	}
+	return next(0)()
+}
+
+// recoverHandlerPanic recovers from a panic in a handler, and returns it in errPtr.
+func (c *Conn) recoverHandlerPanic(name string, errPtr *error) {
+	if r := recover(); r != nil {
+		stack := string(runtimeDebug.Stack())
+		c.Log("Error while handling", name, r, stack)
+		if rErr, ok := r.(error); ok {
+			*errPtr = rErr
+		} else {
+			*errPtr = errs.New(errs.Info{"Recovery": r}, fmt.Sprint(r))
+		}
+	}
+}
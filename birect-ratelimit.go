@@ -0,0 +1,240 @@
+package birect
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitedCode is the ResponseError.Code of requests that get rejected by a RateLimit.
+const RateLimitedCode = "RATE_LIMITED"
+
+// RateLimit is a token-bucket rate limit for requests. Every bucket holds up to Burst
+// tokens, and refills at Rate tokens per second. Each request takes a token from its
+// bucket, or gets rejected with a retryable ResponseError with Code RateLimitedCode and
+// a RetryAfter hint if the bucket is empty.
+//
+// Requests that get rejected by one rate limit don't take tokens from any other.
+//
+// Requests share a bucket if they have the same keys, e.g
+//
+//	server.RateLimit(birect.RateLimit{Rate: 10, Burst: 20, By: []birect.RateLimitKey{birect.PerInfo("UserID")}})
+//	server.RateLimit(birect.RateLimit{Rate: 1, Burst: 1, Names: []string{"Export"}, By: []birect.RateLimitKey{birect.PerConn}})
+type RateLimit struct {
+	// Rate and Burst must be positive.
+	Rate  float64
+	Burst int
+	// Names limits the rate limit to requests with the given names. Empty means all requests.
+	Names []string
+	// By selects the bucket of a request. Empty means a single bucket for all requests.
+	By []RateLimitKey
+}
+
+// RateLimitKey returns the part of a request's bucket key that it selects by.
+type RateLimitKey func(req *HandlerReq) string
+
+// PerConn gives every connection its own bucket.
+func PerConn(req *HandlerReq) string {
+	return strconv.FormatUint(req.Conn.id, 10)
+}
+
+// PerName gives every request name its own bucket.
+func PerName(req *HandlerReq) string {
+	return req.Name
+}
+
+// PerInfo gives every value of the given Info key, as returned by Authenticate, its own bucket,
+// e.g a user ID. Values set on the Conn's Info after it connected are not taken into account.
+func PerInfo(infoKey string) RateLimitKey {
+	return func(req *HandlerReq) string {
+		return fmt.Sprint(req.Conn.authInfo[infoKey])
+	}
+}
+
+// RateLimit adds rate limits for the Handler's requests and streams. See RateLimit. Rate
+// limits are checked before a request takes a handler slot or a place in the queue, and
+// before any Middleware runs. RateLimit panics if a rate limit's Rate or Burst is not positive.
+func (s *Handler) RateLimit(rateLimits ...RateLimit) {
+	limiters := make([]*rateLimiter, len(rateLimits))
+	for i, rateLimit := range rateLimits {
+		if rateLimit.Rate <= 0 || rateLimit.Burst <= 0 {
+			panic(fmt.Sprintf("birect: RateLimit Rate and Burst must be positive, got %v and %v", rateLimit.Rate, rateLimit.Burst))
+		}
+		limiters[i] = newRateLimiter(rateLimit)
+	}
+	s.rateLimits.add(limiters)
+}
+
+// Internal
+///////////
+
+// rateLimits is shared between a Handler and all of its Conns. It is safe for concurrent use.
+type rateLimits struct {
+	mutex    *sync.Mutex
+	limiters []*rateLimiter
+}
+
+func newRateLimits() *rateLimits {
+	return &rateLimits{&sync.Mutex{}, nil}
+}
+
+func (r *rateLimits) add(limiters []*rateLimiter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.limiters = append(r.limiters[:len(r.limiters):len(r.limiters)], limiters...)
+}
+
+func (r *rateLimits) get() []*rateLimiter {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.limiters
+}
+
+// take takes a token for req from every rate limit that applies to it. If one of them is
+// out of tokens, take puts back the tokens it took from the others and returns a rate limited
+// error. Otherwise it returns a func that puts back all the tokens, for when a later stage
+// rejects req.
+func (r *rateLimits) take(req *HandlerReq) (refund func(), err error) {
+	type takenToken struct {
+		limiter *rateLimiter
+		key     string
+	}
+	var taken []takenToken
+	refund = func() {
+		now := time.Now()
+		for _, token := range taken {
+			token.limiter.refund(token.key, now)
+		}
+	}
+	for _, limiter := range r.get() {
+		if len(limiter.names) > 0 && !limiter.names[req.Name] {
+			continue
+		}
+		key := limiter.bucketKey(req)
+		if retryAfter, ok := limiter.take(key, req.Conn.id, time.Now()); !ok {
+			refund()
+			return nil, &ResponseError{Message: "Too many requests - please slow down.", Code: RateLimitedCode, Retryable: true, RetryAfter: retryAfter}
+		}
+		taken = append(taken, takenToken{limiter, key})
+	}
+	return refund, nil
+}
+
+// removeConn drops the buckets of the rate limits that are per connection, once conn has closed.
+func (r *rateLimits) removeConn(conn *Conn) {
+	for _, limiter := range r.get() {
+		limiter.removeConn(conn.id)
+	}
+}
+
+type rateLimiter struct {
+	RateLimit
+	names       map[string]bool
+	perConn     bool // Set if one of the keys is PerConn, so that every bucket belongs to a single connection
+	mutex       *sync.Mutex
+	buckets     map[string]*tokenBucket
+	connBuckets map[uint64]map[string]bool // Bucket keys by Conn ID, if perConn
+	nextSweep   int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+const minRateLimitSweep = 1024
+
+func newRateLimiter(rateLimit RateLimit) *rateLimiter {
+	names := make(map[string]bool, len(rateLimit.Names))
+	for _, name := range rateLimit.Names {
+		names[name] = true
+	}
+	perConn := false
+	for _, key := range rateLimit.By {
+		if reflect.ValueOf(key).Pointer() == reflect.ValueOf(PerConn).Pointer() {
+			perConn = true
+		}
+	}
+	return &rateLimiter{rateLimit, names, perConn, &sync.Mutex{}, make(map[string]*tokenBucket), make(map[uint64]map[string]bool), minRateLimitSweep}
+}
+
+func (r *rateLimiter) bucketKey(req *HandlerReq) string {
+	keys := make([]string, len(r.By))
+	for i, key := range r.By {
+		keys[i] = key(req)
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// take takes a token from the bucket with the given key. If the bucket is empty, take
+// returns how long it takes until the next token is available.
+func (r *rateLimiter) take(key string, connID uint64, now time.Time) (retryAfter time.Duration, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	bucket := r.buckets[key]
+	if bucket == nil {
+		if len(r.buckets) >= r.nextSweep {
+			r.sweep(now)
+		}
+		bucket = &tokenBucket{float64(r.Burst), now}
+		r.buckets[key] = bucket
+		if r.perConn {
+			if r.connBuckets[connID] == nil {
+				r.connBuckets[connID] = make(map[string]bool)
+			}
+			r.connBuckets[connID][key] = true
+		}
+	}
+	bucket.refill(now, r.Rate, r.Burst)
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / r.Rate * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// refund puts back a token taken from the bucket with the given key.
+func (r *rateLimiter) refund(key string, now time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if bucket := r.buckets[key]; bucket != nil {
+		bucket.refill(now, r.Rate, r.Burst)
+		if bucket.tokens++; bucket.tokens > float64(r.Burst) {
+			bucket.tokens = float64(r.Burst)
+		}
+	}
+}
+
+// removeConn drops the buckets of the connection with the given ID.
+func (r *rateLimiter) removeConn(connID uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for key := range r.connBuckets[connID] {
+		delete(r.buckets, key)
+	}
+	delete(r.connBuckets, connID)
+}
+
+// sweep removes the buckets that have refilled completely.
+func (r *rateLimiter) sweep(now time.Time) {
+	for key, bucket := range r.buckets {
+		if bucket.refill(now, r.Rate, r.Burst); bucket.tokens >= float64(r.Burst) {
+			delete(r.buckets, key)
+		}
+	}
+	r.nextSweep = 2 * len(r.buckets)
+	if r.nextSweep < minRateLimitSweep {
+		r.nextSweep = minRateLimitSweep
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time, rate float64, burst int) {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+}
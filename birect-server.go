@@ -1,22 +1,45 @@
 package birect
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
 	"sync"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
 	"github.com/marcuswestin/go-ws"
 )
 
 // Handler is used register request handlers (for requests sent from clients),
 // and to accept incoming connections from birect clients.
 type Handler struct {
-	jsonReqHandlerMap
-	protoReqHandlerMap
+	handlerMaps
+	connConfig
 	connByWSConnMutex *sync.Mutex
 	connByWSConn      map[*ws.Conn]*Conn
 	ConnectHandler    func(*Conn)
 	DisconnectHandler func(*Conn)
+
+	// Authenticate, if set, gets called for every incoming HTTP request before it gets
+	// upgraded. Returning an error rejects the upgrade with http.StatusUnauthorized, or
+	// with the status of an *UpgradeError. The returned Info prefills the Conn's Info.
+	Authenticate func(r *http.Request) (Info, error)
+
+	httpServer *http.Server // Guarded by connByWSConnMutex. Set by ListenAndServe
+	lastConnID uint64       // Guarded by connByWSConnMutex
+}
+
+// UpgradeError rejects an upgrade from Handler.Authenticate with the given HTTP status.
+type UpgradeError struct {
+	Status  int
+	Message string
+}
+
+// Error returns the message of the error.
+func (e *UpgradeError) Error() string {
+	return e.Message
 }
 
 // UpgradeRequests will upgrade all incoming HTTP requests that match `pattern`
@@ -24,7 +47,7 @@ type Handler struct {
 // call http.ListenAndServe()
 func UpgradeRequests(pattern string) *Handler {
 	handler := newHandler()
-	ws.UpgradeRequests(pattern, getEventHandler(handler))
+	http.Handle(pattern, handler)
 	return handler
 }
 
@@ -41,12 +64,15 @@ type Server struct {
 
 func newHandler() *Handler {
 	return &Handler{
-		make(jsonReqHandlerMap),
-		make(protoReqHandlerMap),
+		newHandlerMaps(),
+		newConnConfig(),
 		&sync.Mutex{},
 		make(map[*ws.Conn]*Conn, 10000),
 		func(*Conn) {},
 		func(*Conn) {},
+		nil,
+		nil,
+		0,
 	}
 }
 
@@ -55,7 +81,7 @@ func newHandler() *Handler {
 func (s *Handler) ListenAndServe(address string) (errChan chan error) {
 	errChan = make(chan error)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", ws.UpgradeHandlerFunc(getEventHandler(s)))
+	mux.Handle("/", s)
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		go func() {
@@ -63,25 +89,58 @@ func (s *Handler) ListenAndServe(address string) (errChan chan error) {
 		}()
 		return
 	}
+	httpServer := &http.Server{Handler: mux}
+	s.connByWSConnMutex.Lock()
+	s.httpServer = httpServer
+	s.connByWSConnMutex.Unlock()
 	go func() {
-		errChan <- http.Serve(listener, mux)
+		errChan <- httpServer.Serve(listener)
 	}()
 	return errChan
 }
 
-func getEventHandler(server *Handler) ws.EventHandler {
+// ServeHTTP authenticates and upgrades the incoming HTTP request to a birect connection.
+func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.isShuttingDown() {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	var info Info
+	if s.Authenticate != nil {
+		var err error
+		info, err = s.Authenticate(r)
+		if err != nil {
+			status, message := http.StatusUnauthorized, ""
+			if upgradeErr, ok := err.(*UpgradeError); ok {
+				status, message = upgradeErr.Status, upgradeErr.Message
+			}
+			if message == "" {
+				message = http.StatusText(status)
+			}
+			http.Error(w, message, status)
+			return
+		}
+	}
+	ws.UpgradeHandlerFunc(getEventHandler(s, info, newConnRequest(r)))(w, r)
+}
+
+func getEventHandler(server *Handler, info Info, request *ConnRequest) ws.EventHandler {
 	return func(event *ws.Event, wsConn *ws.Conn) {
 		switch event.Type {
 		case ws.Connected:
-			server.registerConn(wsConn)
+			server.registerConn(wsConn, info, request)
 		case ws.BinaryMessage:
 			if conn := server.getConn(wsConn); conn != nil {
 				conn.readAndHandleWireWrapperReader(event)
 			}
 		case ws.NetError:
-			server.getConn(wsConn).Log("Net error")
+			if conn := server.getConn(wsConn); conn != nil {
+				conn.Log("Net error")
+			}
 		case ws.Disconnected:
-			server.getConn(wsConn).Log("Disconnected")
+			if conn := server.getConn(wsConn); conn != nil {
+				conn.Log("Disconnected")
+			}
 			server.deregisterConn(wsConn)
 		default:
 			panic("birect.Handler unknown event: " + event.String())
@@ -107,14 +166,87 @@ func (s *Handler) Conns() (conns []*Conn) {
 	return
 }
 
+// BroadcastJSON sends a one-way message for the JSONMessageHandler with the given `name` to every
+// connection for which filter returns true, or to all connections if filter is nil. The message
+// is encoded once, and the same bytes are sent to every connection.
+func (s *Handler) BroadcastJSON(name string, valueObj interface{}, filter func(*Conn) bool) error {
+	data, err := json.Marshal(valueObj)
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	return s.broadcast(&wire.Message{Type: wire.DataType_JSON, Name: name, Data: data}, filter)
+}
+
+// BroadcastProto sends a one-way message for the ProtoMessageHandler with the given `name` to
+// every connection for which filter returns true. See BroadcastJSON.
+func (s *Handler) BroadcastProto(name string, valueObj Proto, filter func(*Conn) bool) error {
+	data, err := proto.Marshal(valueObj)
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	return s.broadcast(&wire.Message{Type: wire.DataType_Proto, Name: name, Data: data}, filter)
+}
+
 // Internal
 ///////////
 
-func (s *Handler) registerConn(wsConn *ws.Conn) {
+func (s *Handler) broadcast(wireMsg *wire.Message, filter func(*Conn) bool) error {
+	wrapperData, err := proto.Marshal(&wire.Wrapper{
+		Content: &wire.Wrapper_Message{Message: wireMsg},
+	})
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	var conns []*Conn
+	for _, conn := range s.Conns() {
+		if filter == nil || filter(conn) {
+			conns = append(conns, conn)
+		}
+	}
+	sendToConns(conns, wrapperData, "broadcast", wireMsg.Name)
+	return nil
+}
+
+// sendToConns sends wrapperData to all of conns at once, so that a slow connection does not
+// hold up the others. It returns once every send is done, so that consecutive calls arrive
+// in order.
+func sendToConns(conns []*Conn, wrapperData []byte, kind string, name string) {
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *Conn) {
+			defer wg.Done()
+			if err := conn.sendWrapperData(wrapperData); err != nil {
+				conn.Log("Unable to send", kind, name, err)
+			}
+		}(conn)
+	}
+	wg.Wait()
+}
+
+func (s *Handler) registerConn(wsConn *ws.Conn, info Info, request *ConnRequest) {
 	s.connByWSConnMutex.Lock()
 	defer s.connByWSConnMutex.Unlock()
-	conn := newConn(wsConn, s.jsonReqHandlerMap, s.protoReqHandlerMap)
+	conn := newConn(wsConn, s.handlerMaps, s.connConfig)
+	s.lastConnID++
+	conn.id = s.lastConnID
+	conn.request = request
+	conn.authInfo = newInfo()
+	for key, val := range info {
+		conn.Info.Set(key, val)
+		conn.authInfo.Set(key, val)
+	}
+	if s.shutdown.isShuttingDown() {
+		// The connection got upgraded while Shutdown was closing connections
+		conn.Close()
+		return
+	}
 	s.connByWSConn[wsConn] = conn
+	conn.startHeartbeat(s.heartbeat.get())
+	if collector := s.metrics.get(); collector != nil {
+		collector.ConnOpened(conn)
+		conn.metricsCounted = true
+	}
 	if s.ConnectHandler != nil {
 		defer s.ConnectHandler(conn)
 	}
@@ -124,6 +256,15 @@ func (s *Handler) deregisterConn(wsConn *ws.Conn) {
 	defer s.connByWSConnMutex.Unlock()
 	conn := s.connByWSConn[wsConn]
 	delete(s.connByWSConn, wsConn)
+	if conn == nil {
+		return
+	}
+	conn.close()
+	s.topics.removeConn(conn)
+	s.rateLimits.removeConn(conn)
+	if collector := s.metrics.get(); collector != nil && conn.metricsCounted {
+		collector.ConnClosed(conn)
+	}
 	if s.DisconnectHandler != nil {
 		defer s.DisconnectHandler(conn)
 	}
@@ -0,0 +1,125 @@
+package birect
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-errs"
+)
+
+// RegisterService registers every exported method of svc that has one of the signatures
+//
+//	func (s *Service) Method(ctx context.Context, params P) (res R, err error)
+//	func (s *Service) Method(ctx context.Context, conn *Conn, params P) (res R, err error)
+//
+// as a request handler named "Service.Method". Methods whose P and R are both Protos get
+// registered as proto request handlers, and all others as JSON request handlers. Methods
+// with other signatures are skipped. RegisterService returns an error if svc has no
+// methods with a supported signature.
+func (s *Handler) RegisterService(svc interface{}) error {
+	svcValue := reflect.ValueOf(svc)
+	svcName := reflect.Indirect(svcValue).Type().Name()
+	registered := 0
+	for i := 0; i < svcValue.NumMethod(); i++ {
+		method := svcValue.Type().Method(i)
+		if method.PkgPath != "" {
+			continue // Unexported
+		}
+		serviceMethod, ok := newServiceMethod(svcValue.Method(i))
+		if !ok {
+			continue
+		}
+		name := svcName + "." + method.Name
+		if serviceMethod.isProto() {
+			s.HandleProtoReq(name, serviceMethod.handleProtoReq)
+		} else {
+			s.HandleJSONReq(name, serviceMethod.handleJSONReq)
+		}
+		registered++
+	}
+	if registered == 0 {
+		return errs.New(errs.Info{"Service": svcName}, "Service has no methods with a supported signature")
+	}
+	return nil
+}
+
+// Internal
+///////////
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	connType    = reflect.TypeOf((*Conn)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	protoType   = reflect.TypeOf((*Proto)(nil)).Elem()
+)
+
+type serviceMethod struct {
+	fn         reflect.Value
+	takesConn  bool
+	paramsType reflect.Type
+	resType    reflect.Type
+}
+
+func newServiceMethod(fn reflect.Value) (*serviceMethod, bool) {
+	fnType := fn.Type()
+	if fnType.NumIn() < 2 || fnType.NumIn() > 3 || fnType.NumOut() != 2 {
+		return nil, false
+	}
+	if fnType.In(0) != contextType || fnType.Out(1) != errorType {
+		return nil, false
+	}
+	takesConn := fnType.NumIn() == 3
+	if takesConn && fnType.In(1) != connType {
+		return nil, false
+	}
+	return &serviceMethod{fn, takesConn, fnType.In(fnType.NumIn() - 1), fnType.Out(0)}, true
+}
+
+func (m *serviceMethod) isProto() bool {
+	return m.paramsType.Kind() == reflect.Ptr && m.paramsType.Implements(protoType) &&
+		m.resType.Kind() == reflect.Ptr && m.resType.Implements(protoType)
+}
+
+// newParams returns a pointer to a new value of the params type, along with the value to call with.
+func (m *serviceMethod) newParams() (paramsPtr interface{}, paramsValue func() reflect.Value) {
+	if m.paramsType.Kind() == reflect.Ptr {
+		params := reflect.New(m.paramsType.Elem())
+		return params.Interface(), func() reflect.Value { return params }
+	}
+	params := reflect.New(m.paramsType)
+	return params.Interface(), params.Elem
+}
+
+func (m *serviceMethod) call(ctx context.Context, conn *Conn, params reflect.Value) (interface{}, error) {
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if m.takesConn {
+		args = append(args, reflect.ValueOf(conn))
+	}
+	out := m.fn.Call(append(args, params))
+	err, _ := out[1].Interface().(error)
+	return out[0].Interface(), err
+}
+
+func (m *serviceMethod) handleJSONReq(req *JSONReq) (interface{}, error) {
+	paramsPtr, paramsValue := m.newParams()
+	if len(req.data) > 0 {
+		if err := json.Unmarshal(req.data, paramsPtr); err != nil {
+			return nil, errs.Wrap(err, nil, "Unable to parse params")
+		}
+	}
+	return m.call(req.Context(), req.Conn, paramsValue())
+}
+
+func (m *serviceMethod) handleProtoReq(req *ProtoReq) (Proto, error) {
+	paramsPtr, paramsValue := m.newParams()
+	if err := proto.Unmarshal(req.data, paramsPtr.(Proto)); err != nil {
+		return nil, errs.Wrap(err, nil, "Unable to parse params")
+	}
+	res, err := m.call(req.Context(), req.Conn, paramsValue())
+	if err != nil {
+		return nil, err
+	}
+	return res.(Proto), nil
+}
@@ -0,0 +1,137 @@
+package birect
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// ShuttingDownCode is the ResponseError.Code of requests and streams that get rejected because the Handler is shutting down.
+const ShuttingDownCode = "SHUTTING_DOWN"
+
+// Shutdown gracefully shuts down the handler, e.g for a zero-downtime deploy. It stops
+// accepting new connections, and sends every connection a going away frame. Clients
+// then queue new requests in their OfflineQueue until they have reconnected, presumably
+// to another server. Requests and streams that arrive in the meantime get rejected with a
+// retryable ResponseError with Code ShuttingDownCode. Shutdown waits for in-flight request
+// handlers and accepted streams to finish, or for ctx to be done, and then closes all
+// remaining connections.
+//
+// Shutdown returns ctx.Err() if ctx is done before the request handlers finish. If the
+// handler was started with ListenAndServe, its errChan receives http.ErrServerClosed.
+func (s *Handler) Shutdown(ctx context.Context) (err error) {
+	s.connByWSConnMutex.Lock()
+	idle := s.shutdown.start()
+	httpServer := s.httpServer
+	s.connByWSConnMutex.Unlock()
+
+	if httpServer != nil {
+		// Upgraded connections have been hijacked, so this only stops the listener
+		httpServer.Shutdown(ctx)
+	}
+
+	conns := s.Conns()
+	for _, conn := range conns {
+		conn.Log("Going away")
+		if sendErr := conn.sendWrapper(&wire.Wrapper{Content: &wire.Wrapper_GoingAway{GoingAway: &wire.GoingAway{}}}); sendErr != nil {
+			conn.Log("Unable to send going away", sendErr)
+		}
+	}
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	for _, conn := range s.Conns() {
+		conn.Close()
+	}
+	return
+}
+
+// Internal
+///////////
+
+// shutdownRetryAfter is the RetryAfter hint of requests that get rejected during shutdown,
+// which gives clients time to reconnect.
+const shutdownRetryAfter = time.Second
+
+func (s *Handler) isShuttingDown() bool {
+	return s.shutdown.isShuttingDown()
+}
+
+// shutdownState tracks the requests and streams that are in flight on all of a Handler's
+// Conns, so that Shutdown can reject new ones and wait for the rest to finish.
+type shutdownState struct {
+	mutex        *sync.Mutex
+	shuttingDown bool
+	inFlight     int
+	idle         chan struct{} // Closed once shutting down with nothing in flight
+}
+
+func newShutdownState() *shutdownState {
+	return &shutdownState{&sync.Mutex{}, false, 0, make(chan struct{})}
+}
+
+// start starts shutting down, and returns a channel that gets closed once nothing is in flight.
+func (s *shutdownState) start() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.shuttingDown {
+		s.shuttingDown = true
+		if s.inFlight == 0 {
+			close(s.idle)
+		}
+	}
+	return s.idle
+}
+
+func (s *shutdownState) isShuttingDown() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.shuttingDown
+}
+
+// startReq counts a new request or stream as in flight, unless shutdown has started.
+func (s *shutdownState) startReq() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+func (s *shutdownState) doneReq() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.inFlight--; s.inFlight == 0 && s.shuttingDown {
+		close(s.idle)
+	}
+}
+
+func newShuttingDownError() *ResponseError {
+	return &ResponseError{Message: "Server is shutting down - please try again.", Code: ShuttingDownCode, Retryable: true, RetryAfter: shutdownRetryAfter}
+}
+
+func (c *Conn) handleGoingAway(wireGoingAway *wire.GoingAway) {
+	c.Log("HANDLE GOING AWAY")
+	if c.onGoingAway != nil {
+		c.onGoingAway(c)
+	}
+}
+
+// goingAway stops the client from sending new requests on conn, which the server
+// closes once it has finished handling the requests that are in flight.
+func (client *Client) goingAway(conn *Conn) {
+	client.connMutex.Lock()
+	defer client.connMutex.Unlock()
+	if client.conn == conn {
+		client.connected = false
+		client.lostConn = conn
+	}
+}
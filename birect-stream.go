@@ -0,0 +1,452 @@
+package birect
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
+
+// StreamHandler functions get called for every stream opened with OpenStream.
+// When the handler returns the stream gets closed for sending, with the returned error if any.
+// stream.Context() gets cancelled if the opener closes the stream, or the Conn closes.
+type StreamHandler func(stream *Stream) (err error)
+
+// OpenStream opens a bidirectional stream to the StreamHandler with the given `name`.
+// Both sides may send and receive until they call CloseSend.
+func (c *Conn) OpenStream(name string) (stream *Stream, err error) {
+	return c.OpenStreamContext(context.Background(), name)
+}
+
+// OpenStreamContext is like OpenStream, but closes the stream if ctx is cancelled.
+func (c *Conn) OpenStreamContext(ctx context.Context, name string) (stream *Stream, err error) {
+	id := reqID(atomic.AddUint32((*uint32)(&c.lastStreamID), 1))
+	wireOpen := &wire.StreamOpen{StreamId: uint32(id), Name: name, Traceparent: traceparentFromContext(ctx)}
+	endSpan := c.startClientSpan(ctx, name, &wireOpen.Traceparent)
+	stream, err = c.newStream(ctx, name, id, true, endSpan)
+	if err != nil {
+		endSpan(err)
+		return
+	}
+	c.Log("OPEN STREAM", name, "StreamID:", id)
+	err = c.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamOpen{StreamOpen: wireOpen},
+	})
+	if err != nil {
+		stream.release(err)
+		return nil, errs.Wrap(err, nil)
+	}
+	return stream, nil
+}
+
+// Stream is a bidirectional stream of JSON and proto values, opened with OpenStream.
+// Send and Recv may be called concurrently with each other, and with Close.
+type Stream struct {
+	Conn       *Conn
+	Name       string
+	id         reqID
+	isOpener   bool
+	ctx        context.Context
+	cancel     context.CancelFunc
+	frames     *streamFrames
+	credits    *sendCredits
+	recvErr    error
+	consumed   uint32          // Values received since credit was last granted
+	endSpan    func(err error) // Called once the stream has been released. Only the first call counts
+	mutex      *sync.Mutex     // Guards sendClosed, recvEnded and closeErr
+	sendClosed bool
+	recvEnded  bool
+	closeErr   error // The error that the other side closed with, if any
+}
+
+// SendJSON sends valueObj as the next value of the stream.
+func (s *Stream) SendJSON(valueObj interface{}) error {
+	data, err := json.Marshal(valueObj)
+	if err != nil {
+		return err
+	}
+	return s.send(wire.DataType_JSON, data)
+}
+
+// SendProto sends valueObj as the next value of the stream.
+func (s *Stream) SendProto(valueObj Proto) error {
+	data, err := proto.Marshal(valueObj)
+	if err != nil {
+		return err
+	}
+	return s.send(wire.DataType_Proto, data)
+}
+
+// Recv parses the next value of the stream into valuePtr. valuePtr should match the
+// type that the other side sent with, i.e a JSON-parsable struct for SendJSON, and a
+// Proto for SendProto. Recv returns io.EOF once the other side has called CloseSend,
+// or the other side's handler error if it ended with one. If the stream gets closed
+// or cancelled, Recv returns the values it has already received, and then io.EOF
+// or the context's error.
+func (s *Stream) Recv(valuePtr interface{}) error {
+	if s.recvErr != nil {
+		return s.recvErr
+	}
+	var frame streamFrame
+	select {
+	case frame = <-s.frames.frames:
+	default:
+		// Only give up once all buffered frames have been read
+		select {
+		case frame = <-s.frames.frames:
+		case <-s.frames.done:
+			return s.endRecv(s.frames.err)
+		}
+	}
+	if frame.end != nil {
+		if frame.end.IsError {
+			return s.endRecv(newResponseError(frame.end.Error, frame.end.Data))
+		}
+		return s.endRecv(io.EOF)
+	}
+	s.grantCredit()
+	return errs.Wrap(decodeWireData(frame.chunk.Type, frame.chunk.Data, valuePtr), nil)
+}
+
+// CloseSend closes the stream for sending. The other side's Recv returns io.EOF once
+// it has received all values. The stream may still receive until the other side closes.
+func (s *Stream) CloseSend() error {
+	return s.closeSend(nil)
+}
+
+// Close closes the stream in both directions, and tells the other side to stop sending.
+// Streams that are not read until the other side closes them should always get closed,
+// or they stay open until the Conn closes.
+func (s *Stream) Close() error {
+	return s.stop(io.EOF)
+}
+
+// Context returns the stream's context. It gets cancelled once the stream is closed
+// in both directions, or cancelled by either side, or its Conn closes.
+func (s *Stream) Context() context.Context {
+	return s.ctx
+}
+
+// Internal
+///////////
+
+type streamHandlerMap map[string]StreamHandler
+
+func (m streamHandlerMap) HandleStream(streamName string, handler StreamHandler) {
+	m[streamName] = handler
+}
+
+// newStream registers a new stream with the Conn, and releases it when ctx is done.
+// endSpan gets called with the stream's error once it has been released.
+func (c *Conn) newStream(ctx context.Context, name string, id reqID, isOpener bool, endSpan func(err error)) (*Stream, error) {
+	frames, err := c.ownStreams(isOpener).add(id)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var spanOnce sync.Once
+	endSpanOnce := func(err error) {
+		spanOnce.Do(func() { endSpan(err) })
+	}
+	stream := &Stream{c, name, id, isOpener, ctx, cancel, frames, newSendCredits(streamBufferSize), nil, 0, endSpanOnce, &sync.Mutex{}, false, false, nil}
+	if err := c.liveStreams.add(stream); err != nil {
+		cancel()
+		c.ownStreams(isOpener).remove(id, err)
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		stream.stop(ctx.Err())
+	}()
+	return stream, nil
+}
+
+func (s *Stream) send(dataType wire.DataType, data []byte) error {
+	if s.isSendClosed() {
+		return s.errSendClosed()
+	}
+	// Wait for the other side to make room, rather than overflowing its buffer
+	if err := s.credits.take(s.ctx); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.sendClosed {
+		return s.errSendClosed()
+	}
+	return s.Conn.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamData{StreamData: &wire.StreamData{
+			StreamId:   uint32(s.id),
+			FromOpener: s.isOpener,
+			Type:       dataType,
+			Data:       data,
+		}},
+	})
+}
+
+func (s *Stream) isSendClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sendClosed
+}
+
+func (s *Stream) errSendClosed() error {
+	return errs.New(errs.Info{"Name": s.Name}, "Stream is closed for sending")
+}
+
+func (s *Stream) closeSend(err error) error {
+	s.mutex.Lock()
+	if s.sendClosed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.sendClosed = true
+	ended := s.recvEnded
+	s.mutex.Unlock()
+
+	wireClose := &wire.StreamClose{StreamId: uint32(s.id), FromOpener: s.isOpener}
+	if err != nil {
+		s.Conn.Log("Stream ERROR", s.Name, s.id, err)
+		wireClose.IsError = true
+		wireClose.Error = s.Conn.toWireError(err)
+		wireClose.Data = []byte(wireClose.Error.Message)
+	}
+	sendErr := s.Conn.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamClose{StreamClose: wireClose},
+	})
+	if ended {
+		// Both directions are done
+		s.release(io.EOF)
+	}
+	return sendErr
+}
+
+func (s *Stream) endRecv(err error) error {
+	s.recvErr = err
+	s.mutex.Lock()
+	s.recvEnded = true
+	closed := s.sendClosed
+	s.mutex.Unlock()
+	if closed {
+		// Both directions are done
+		s.release(err)
+	}
+	return err
+}
+
+// grantCredit lets the other side send more values once enough buffered ones have been read.
+func (s *Stream) grantCredit() {
+	s.consumed++
+	if s.consumed < streamCreditBatch {
+		return
+	}
+	s.Conn.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamDataCredit{StreamDataCredit: &wire.StreamDataCredit{
+			StreamId:   uint32(s.id),
+			FromOpener: s.isOpener,
+			Credit:     s.consumed,
+		}},
+	})
+	s.consumed = 0
+}
+
+// stop releases the stream with err, and tells the other side to stop too.
+func (s *Stream) stop(err error) error {
+	if !s.release(err) {
+		return nil
+	}
+	s.Conn.Log("CANCEL STREAM", s.Name, "StreamID:", s.id, err)
+	return s.Conn.sendWrapper(&wire.Wrapper{
+		Content: &wire.Wrapper_StreamCancel{StreamCancel: &wire.StreamCancel{StreamId: uint32(s.id), FromOpener: s.isOpener}},
+	})
+}
+
+// release removes the stream from its Conn, makes Recv fail with err once the buffered
+// values have been read, and makes Send fail. release returns false if the stream had
+// already been released.
+func (s *Stream) release(err error) bool {
+	if !s.Conn.liveStreams.remove(s) {
+		return false
+	}
+	s.mutex.Lock()
+	s.sendClosed = true
+	closeErr := s.closeErr
+	s.mutex.Unlock()
+	s.Conn.ownStreams(s.isOpener).remove(s.id, err)
+	s.cancel()
+	switch {
+	case closeErr != nil:
+		s.endSpan(closeErr)
+	case err == io.EOF || err == context.Canceled:
+		// Closed or cancelled by either side
+		s.endSpan(nil)
+	default:
+		s.endSpan(err)
+	}
+	return true
+}
+
+// streamKey identifies a stream on a Conn. Both sides may open streams with the same id.
+type streamKey struct {
+	id       reqID
+	isOpener bool
+}
+
+// streamSet keeps track of the streams that have not yet been released.
+// It is safe for concurrent use.
+type streamSet struct {
+	mutex   *sync.Mutex
+	streams map[streamKey]*Stream
+	closed  bool
+}
+
+func newStreamSet() *streamSet {
+	return &streamSet{&sync.Mutex{}, make(map[streamKey]*Stream), false}
+}
+
+func (s *streamSet) add(stream *Stream) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrConnClosed
+	}
+	s.streams[streamKey{stream.id, stream.isOpener}] = stream
+	return nil
+}
+
+func (s *streamSet) get(key streamKey) *Stream {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.streams[key]
+}
+
+func (s *streamSet) remove(stream *Stream) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := streamKey{stream.id, stream.isOpener}
+	if s.streams[key] != stream {
+		return false
+	}
+	delete(s.streams, key)
+	return true
+}
+
+func (s *streamSet) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.streams)
+}
+
+// close cancels all streams, as well as any streams added later.
+func (s *streamSet) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	for key, stream := range s.streams {
+		delete(s.streams, key)
+		stream.endSpan(ErrConnClosed)
+		stream.cancel()
+	}
+}
+
+// Frames sent by the opener of a stream are for a stream that this side accepted, and vice versa.
+func (c *Conn) localStreams(fromOpener bool) *pendingStreams {
+	return c.ownStreams(!fromOpener)
+}
+func (c *Conn) ownStreams(isOpener bool) *pendingStreams {
+	if isOpener {
+		return c.openedStreams
+	}
+	return c.acceptedStreams
+}
+func (c *Conn) localStream(id uint32, fromOpener bool) *Stream {
+	return c.liveStreams.get(streamKey{reqID(id), !fromOpener})
+}
+
+func (c *Conn) handleStreamOpen(wireOpen *wire.StreamOpen) {
+	c.Log("HANDLE STREAM OPEN", wireOpen)
+	tracer := c.tracer.get()
+	span := startServerSpan(tracer, wireOpen.Name, wireOpen.Traceparent)
+	endSpan := func(err error) {
+		if span != nil {
+			exportSpan(tracer, span, err)
+		}
+	}
+	ctx := withTrace(context.Background(), wireOpen.Traceparent, span)
+	// The server span lasts until the handler returns, rather than until the stream is released
+	stream, err := c.newStream(ctx, wireOpen.Name, reqID(wireOpen.StreamId), false, func(error) {})
+	if err != nil {
+		c.Log("Unable to accept stream", wireOpen.Name, err)
+		endSpan(err)
+		return
+	}
+	// Stream handlers take up a handler slot like requests, for as long as they run
+	c.runInSlot(stream.ctx, wireOpen.Name, func() {
+		_, err := c.runReqHandler(stream.ctx, wireOpen.Name, func() (interface{}, error) {
+			handler, exists := c.streamHandlerMap[wireOpen.Name]
+			if !exists {
+				return nil, errs.New(nil, "Missing stream handler")
+			}
+			return nil, handler(stream)
+		})
+		endSpan(err)
+		stream.closeSend(wrapHandlerError(err, errs.Info{"HandlerName": wireOpen.Name}))
+		// The handler is done, so the opener should stop sending
+		stream.stop(io.EOF)
+	}, func(err error) {
+		endSpan(err)
+		stream.closeSend(err)
+		stream.stop(io.EOF)
+	}, func() {
+		// The opener closed the stream while it waited, which already stopped it
+		endSpan(stream.ctx.Err())
+	})
+}
+
+func (c *Conn) handleStreamData(wireData *wire.StreamData) {
+	frame := streamFrame{chunk: &wire.StreamChunk{ReqId: wireData.StreamId, Type: wireData.Type, Data: wireData.Data}}
+	switch c.localStreams(wireData.FromOpener).deliver(reqID(wireData.StreamId), frame) {
+	case frameUnknown:
+		c.Log("Dropping stream data for unknown stream", wireData.StreamId)
+	case frameOverflow:
+		c.Log("CANCEL STREAM - other side sent more values than granted", "StreamID:", wireData.StreamId)
+		if stream := c.localStream(wireData.StreamId, wireData.FromOpener); stream != nil {
+			stream.stop(ErrStreamOverflow)
+		}
+	}
+}
+
+func (c *Conn) handleStreamClose(wireClose *wire.StreamClose) {
+	if wireClose.IsError {
+		if stream := c.localStream(wireClose.StreamId, wireClose.FromOpener); stream != nil {
+			stream.mutex.Lock()
+			stream.closeErr = newResponseError(wireClose.Error, wireClose.Data)
+			stream.mutex.Unlock()
+		}
+	}
+	frame := streamFrame{end: &wire.StreamEnd{ReqId: wireClose.StreamId, IsError: wireClose.IsError, Type: wire.DataType_Text, Data: wireClose.Data, Error: wireClose.Error}}
+	if c.localStreams(wireClose.FromOpener).deliver(reqID(wireClose.StreamId), frame) != frameDelivered {
+		c.Log("Dropping stream close for unknown stream", wireClose.StreamId)
+	}
+}
+
+func (c *Conn) handleStreamDataCredit(wireCredit *wire.StreamDataCredit) {
+	stream := c.localStream(wireCredit.StreamId, wireCredit.FromOpener)
+	if stream == nil {
+		c.Log("Dropping stream credit for unknown stream", wireCredit.StreamId)
+		return
+	}
+	stream.credits.grant(wireCredit.Credit)
+}
+
+func (c *Conn) handleStreamCancel(wireCancel *wire.StreamCancel) {
+	c.Log("HANDLE STREAM CANCEL", wireCancel)
+	if stream := c.localStream(wireCancel.StreamId, wireCancel.FromOpener); stream != nil {
+		stream.release(context.Canceled)
+	}
+}
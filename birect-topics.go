@@ -0,0 +1,335 @@
+package birect
+
+import (
+	"encoding/json"
+	runtimeDebug "runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
+
+// TopicHandler functions get called with every value published to a subscribed topic.
+// Like message handlers, they get called in order on the connection's read loop.
+type TopicHandler func(pub *Publication)
+
+// Publication wraps a value published with Handler.Publish. Use ParseValue to access it.
+type Publication struct {
+	Conn     *Conn
+	Topic    string
+	dataType wire.DataType
+	data     []byte
+}
+
+// ParseValue parses the published value into valuePtr. valuePtr should be a JSON-parsable
+// struct pointer for values published with Publish, and a Proto for PublishProto.
+func (p *Publication) ParseValue(valuePtr interface{}) error {
+	return errs.Wrap(decodeWireData(p.dataType, p.data, valuePtr), nil)
+}
+
+// Publish sends valueObj as JSON to all connections subscribed to the given topic.
+// Topics are dot-separated, e.g "orders.eu.created". Subscription patterns may use
+// `*` to match any single segment, e.g "orders.*.created", and a trailing `>` to
+// match one or more segments, e.g "orders.>".
+//
+// Publish returns without waiting for the value to be sent, so that slow connections
+// do not hold up the publisher. Every connection receives publications in the order
+// that they were published.
+func (s *Handler) Publish(topic string, valueObj interface{}) error {
+	data, err := json.Marshal(valueObj)
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	return s.publish(&wire.Publication{Topic: topic, Type: wire.DataType_JSON, Data: data})
+}
+
+// PublishProto sends valueObj to all connections subscribed to the given topic. See Publish.
+func (s *Handler) PublishProto(topic string, valueObj Proto) error {
+	data, err := proto.Marshal(valueObj)
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	return s.publish(&wire.Publication{Topic: topic, Type: wire.DataType_Proto, Data: data})
+}
+
+// RetainTopics makes topics that match any of the given patterns retain their last
+// published value. New subscribers receive the retained values as soon as they subscribe.
+func (s *Handler) RetainTopics(patterns ...string) {
+	s.topics.retain(patterns)
+}
+
+// Subscribe subscribes the client to all topics that match pattern, and calls handler
+// with every value published to them. See Handler.Publish for the pattern syntax.
+// Subscriptions are kept across reconnects. If the client is reconnecting, the
+// subscription is sent once it has reconnected.
+//
+// Subscribe returns once the subscription has been sent, without waiting for the server,
+// so it may be called from handlers. The server handles the subscription before any
+// requests or messages that the client sends afterwards, and sends matching retained
+// values before any other publications.
+func (client *Client) Subscribe(pattern string, handler TopicHandler) error {
+	client.subscriptions.add(pattern, handler)
+	return client.sendSubscription(pattern, true)
+}
+
+// Unsubscribe removes the subscription for the given pattern. Like Subscribe, it
+// returns once the server has been told, without waiting for it.
+func (client *Client) Unsubscribe(pattern string) error {
+	client.subscriptions.remove(pattern)
+	return client.sendSubscription(pattern, false)
+}
+
+// Internal
+///////////
+
+// matchTopic reports whether topic matches the subscription pattern.
+func matchTopic(pattern string, topic string) bool {
+	patternParts := strings.Split(pattern, ".")
+	topicParts := strings.Split(topic, ".")
+	for i, part := range patternParts {
+		if part == ">" && i == len(patternParts)-1 {
+			return len(topicParts) > i
+		}
+		if i >= len(topicParts) || (part != "*" && part != topicParts[i]) {
+			return false
+		}
+	}
+	return len(topicParts) == len(patternParts)
+}
+
+// topicRegistry keeps track of the topic subscriptions of a Handler's conns,
+// as well as retained values. It is safe for concurrent use.
+type topicRegistry struct {
+	mutex          *sync.Mutex
+	subscriptions  map[*Conn]map[string]bool
+	retainPatterns []string
+	retained       map[string][]byte // Topic -> marshalled wire.Wrapper
+}
+
+func newTopicRegistry() *topicRegistry {
+	return &topicRegistry{&sync.Mutex{}, make(map[*Conn]map[string]bool), nil, make(map[string][]byte)}
+}
+
+func (r *topicRegistry) retain(patterns []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.retainPatterns = append(r.retainPatterns, patterns...)
+}
+
+// subscribe subscribes conn to pattern, and sends it the retained values that match.
+// The retained values get queued while holding the mutex, like publications, so that a
+// concurrent publication can not overtake the retained value that it replaces. subscribe
+// waits for them to be sent, so that they arrive before anything that conn sends afterwards.
+func (r *topicRegistry) subscribe(conn *Conn, pattern string) {
+	r.mutex.Lock()
+	if r.subscriptions[conn] == nil {
+		r.subscriptions[conn] = make(map[string]bool)
+	}
+	r.subscriptions[conn][pattern] = true
+	var sent chan struct{}
+	for topic, wrapperData := range r.retained {
+		if matchTopic(pattern, topic) {
+			sent = make(chan struct{})
+			conn.publications.push(conn, topic, wrapperData, sent)
+		}
+	}
+	r.mutex.Unlock()
+	if sent != nil {
+		<-sent
+	}
+}
+
+func (r *topicRegistry) unsubscribe(conn *Conn, pattern string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.subscriptions[conn], pattern)
+	if len(r.subscriptions[conn]) == 0 {
+		delete(r.subscriptions, conn)
+	}
+}
+
+func (r *topicRegistry) removeConn(conn *Conn) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.subscriptions, conn)
+}
+
+// publish retains wrapperData if the topic should be retained, and queues it for the subscribed conns.
+func (r *topicRegistry) publish(topic string, wrapperData []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, pattern := range r.retainPatterns {
+		if matchTopic(pattern, topic) {
+			r.retained[topic] = wrapperData
+			break
+		}
+	}
+	for conn, patterns := range r.subscriptions {
+		for pattern := range patterns {
+			if matchTopic(pattern, topic) {
+				conn.publications.push(conn, topic, wrapperData, nil)
+				break
+			}
+		}
+	}
+}
+
+// publicationQueue sends a conn's publications in order, in the background. Pushing never
+// blocks, so publishers are not held up by slow connections. It is safe for concurrent use.
+type publicationQueue struct {
+	mutex   *sync.Mutex
+	pending []queuedPublication
+	sending bool
+}
+
+type queuedPublication struct {
+	topic       string
+	wrapperData []byte
+	sent        chan struct{} // Closed once sent, if set
+}
+
+func newPublicationQueue() *publicationQueue {
+	return &publicationQueue{&sync.Mutex{}, nil, false}
+}
+
+func (q *publicationQueue) push(conn *Conn, topic string, wrapperData []byte, sent chan struct{}) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.pending = append(q.pending, queuedPublication{topic, wrapperData, sent})
+	if !q.sending {
+		q.sending = true
+		go q.send(conn)
+	}
+}
+
+// send sends the queued publications until the queue is empty.
+func (q *publicationQueue) send(conn *Conn) {
+	for {
+		q.mutex.Lock()
+		if len(q.pending) == 0 {
+			q.sending = false
+			q.mutex.Unlock()
+			return
+		}
+		pub := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mutex.Unlock()
+
+		if err := conn.sendWrapperData(pub.wrapperData); err != nil {
+			conn.Log("Unable to send publication", pub.topic, err)
+		}
+		if pub.sent != nil {
+			close(pub.sent)
+		}
+	}
+}
+
+func (s *Handler) publish(wirePub *wire.Publication) error {
+	wrapperData, err := proto.Marshal(&wire.Wrapper{
+		Content: &wire.Wrapper_Publication{Publication: wirePub},
+	})
+	if err != nil {
+		return errs.Wrap(err, nil)
+	}
+	s.topics.publish(wirePub.Topic, wrapperData)
+	return nil
+}
+
+// topicSubscriptions keeps track of a Client's TopicHandlers. It is safe for concurrent use.
+type topicSubscriptions struct {
+	mutex    *sync.Mutex
+	handlers map[string]TopicHandler
+}
+
+func newTopicSubscriptions() *topicSubscriptions {
+	return &topicSubscriptions{&sync.Mutex{}, make(map[string]TopicHandler)}
+}
+
+func (s *topicSubscriptions) add(pattern string, handler TopicHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[pattern] = handler
+}
+
+func (s *topicSubscriptions) remove(pattern string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.handlers, pattern)
+}
+
+func (s *topicSubscriptions) patterns() (patterns []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for pattern := range s.handlers {
+		patterns = append(patterns, pattern)
+	}
+	return
+}
+
+func (s *topicSubscriptions) matching(topic string) (handlers []TopicHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for pattern, handler := range s.handlers {
+		if matchTopic(pattern, topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	return
+}
+
+// sendSubscription sends the (un)subscription on the current conn. If that fails, the conn has
+// been lost, and the client's subscriptions get sent again once it has reconnected.
+func (client *Client) sendSubscription(pattern string, subscribe bool) error {
+	if err := client.CurrentConn().sendSubscription(pattern, subscribe); err != nil && client.isClosed() {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// resubscribe sends all of the client's subscriptions on a new conn.
+func (client *Client) resubscribe(conn *Conn) {
+	for _, pattern := range client.subscriptions.patterns() {
+		if err := conn.sendSubscription(pattern, true); err != nil {
+			conn.Log("Unable to resubscribe", pattern, err)
+		}
+	}
+}
+
+// sendSubscription subscribes or unsubscribes from pattern. It does not wait for the
+// server, since it may get called from handlers on the read loop.
+func (c *Conn) sendSubscription(pattern string, subscribe bool) error {
+	wrapper := &wire.Wrapper{
+		Content: &wire.Wrapper_Unsubscribe{Unsubscribe: &wire.Unsubscribe{Pattern: pattern}},
+	}
+	if subscribe {
+		wrapper.Content = &wire.Wrapper_Subscribe{Subscribe: &wire.Subscribe{Pattern: pattern}}
+	}
+	c.Log("SUBSCRIBE", pattern, subscribe)
+	return c.sendWrapper(wrapper)
+}
+
+func (c *Conn) handleSubscribe(wireSub *wire.Subscribe) {
+	c.Log("HANDLE SUBSCRIBE", wireSub)
+	c.topics.subscribe(c, wireSub.Pattern)
+}
+
+func (c *Conn) handleUnsubscribe(wireUnsub *wire.Unsubscribe) {
+	c.Log("HANDLE UNSUBSCRIBE", wireUnsub)
+	c.topics.unsubscribe(c, wireUnsub.Pattern)
+}
+
+func (c *Conn) handlePublication(wirePub *wire.Publication) {
+	c.Log("HANDLE PUBLICATION", wirePub.Topic)
+	defer func() {
+		if r := recover(); r != nil {
+			stack := string(runtimeDebug.Stack())
+			c.Log("Error while handling publication", wirePub.Topic, r, stack)
+		}
+	}()
+	pub := &Publication{c, wirePub.Topic, wirePub.Type, wirePub.Data}
+	for _, handler := range c.subscriptions.matching(wirePub.Topic) {
+		handler(pub)
+	}
+}
@@ -0,0 +1,250 @@
+package birect
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcuswestin/go-errs"
+)
+
+// TraceID identifies a trace, i.e a chain of requests across services.
+type TraceID [16]byte
+
+// SpanID identifies a span, i.e a single request within a trace.
+type SpanID [8]byte
+
+// String returns the trace id as lowercase hex.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String returns the span id as lowercase hex.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// TraceContext is a W3C trace context. Requests and messages that are sent with a context
+// that carries a TraceContext send it along in the W3C traceparent format. Handlers read it
+// with TraceFromContext(req.Context()), so requests that they send continue the trace.
+type TraceContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+}
+
+// TraceFlagSampled is the TraceContext.Flags bit that marks a trace as sampled.
+const TraceFlagSampled byte = 0x01
+
+// ParseTraceparent parses a W3C traceparent, e.g "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(traceparent string) (tc TraceContext, err error) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return tc, errs.New(errs.Info{"Traceparent": traceparent}, "Bad traceparent")
+	}
+	var flags [1]byte
+	if !decodeHex(tc.TraceID[:], parts[1]) || !decodeHex(tc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) || !tc.IsValid() {
+		return TraceContext{}, errs.New(errs.Info{"Traceparent": traceparent}, "Bad traceparent")
+	}
+	tc.Flags = flags[0]
+	return tc, nil
+}
+
+// String returns the trace context in the W3C traceparent format.
+func (tc TraceContext) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// IsValid returns true if neither the trace id nor the span id are all zeroes.
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != TraceID{} && tc.SpanID != SpanID{}
+}
+
+// ContextWithTrace returns a copy of ctx that carries tc.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext returns the trace context carried by ctx, if any.
+func TraceFromContext(ctx context.Context) (tc TraceContext, ok bool) {
+	tc, ok = ctx.Value(traceKey{}).(TraceContext)
+	return
+}
+
+// SpanKind tells whether a Span was recorded by the requesting or the handling side.
+type SpanKind string
+
+// Span kinds
+const (
+	SpanKindClient SpanKind = "client"
+	SpanKindServer SpanKind = "server"
+)
+
+// Span describes a single request, as seen by either the requesting or the handling side.
+// Trace holds the span's own SpanID, and Parent the SpanID of the span that caused it,
+// which is zero for the first span of a trace.
+type Span struct {
+	Name   string
+	Kind   SpanKind
+	Trace  TraceContext
+	Parent SpanID
+	Start  time.Time
+	End    time.Time
+	Err    error
+}
+
+// Tracer gets every span once it ends. Exporting must not block, since it
+// happens on the request's goroutine.
+type Tracer interface {
+	ExportSpan(span Span)
+}
+
+// SetTracer makes the handler record a server span for every request that it handles.
+//
+// Streams are traced like requests. The span of a streaming request lasts until its last
+// chunk has been read. The client span of a bidirectional stream lasts until the stream
+// has been closed in both directions or cancelled, and its server span until its handler
+// returns.
+func (s *Handler) SetTracer(tracer Tracer) {
+	s.tracer.set(tracer)
+}
+
+// SetTracer makes the client record a client span for every request that it sends,
+// and a server span for every request that it handles. Streams are traced as well;
+// see Handler.SetTracer.
+func (client *Client) SetTracer(tracer Tracer) {
+	client.tracer.set(tracer)
+}
+
+// SpanRecorder is a Tracer that keeps all spans in memory, e.g for tests.
+type SpanRecorder struct {
+	mutex *sync.Mutex
+	spans []Span
+}
+
+// NewSpanRecorder returns a new, empty SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{&sync.Mutex{}, nil}
+}
+
+// ExportSpan records the span.
+func (r *SpanRecorder) ExportSpan(span Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+// Spans returns the recorded spans, in the order that they ended.
+func (r *SpanRecorder) Spans() []Span {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Span(nil), r.spans...)
+}
+
+// Internal
+///////////
+
+type traceKey struct{}
+
+func decodeHex(dst []byte, src string) bool {
+	if len(src) != 2*len(dst) || strings.ToLower(src) != src {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
+
+// tracerConfig holds the Tracer of a Handler or Client.
+type tracerConfig struct {
+	mutex  *sync.Mutex
+	tracer Tracer
+}
+
+func newTracerConfig() *tracerConfig {
+	return &tracerConfig{&sync.Mutex{}, nil}
+}
+
+func (t *tracerConfig) set(tracer Tracer) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tracer = tracer
+}
+
+func (t *tracerConfig) get() Tracer {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.tracer
+}
+
+// traceparentFromContext returns the traceparent to send along with requests
+// and messages that are sent with ctx, or "" if ctx carries no trace context.
+func traceparentFromContext(ctx context.Context) string {
+	if tc, ok := TraceFromContext(ctx); ok {
+		return tc.String()
+	}
+	return ""
+}
+
+// startSpan starts a span that continues the trace of parent, if valid, or starts a new trace.
+func startSpan(name string, kind SpanKind, parent TraceContext) *Span {
+	span := &Span{Name: name, Kind: kind, Start: time.Now()}
+	if parent.IsValid() {
+		span.Trace.TraceID = parent.TraceID
+		span.Trace.Flags = parent.Flags
+		span.Parent = parent.SpanID
+	} else {
+		rand.Read(span.Trace.TraceID[:])
+		span.Trace.Flags = TraceFlagSampled
+	}
+	rand.Read(span.Trace.SpanID[:])
+	return span
+}
+
+// startClientSpan records a client span for the request or stream with the given name if
+// the conn has a tracer, and sets traceparent to the span's trace context, to be sent along.
+// Call the returned func once the response has arrived, or the stream has ended.
+func (c *Conn) startClientSpan(ctx context.Context, name string, traceparent *string) (end func(err error)) {
+	tracer := c.tracer.get()
+	if tracer == nil {
+		return func(error) {}
+	}
+	parent, _ := TraceFromContext(ctx)
+	span := startSpan(name, SpanKindClient, parent)
+	*traceparent = span.Trace.String()
+	return func(err error) {
+		exportSpan(tracer, span, err)
+	}
+}
+
+// startServerSpan returns a server span for the incoming request or stream with the
+// given name and traceparent, or nil if tracer is nil.
+func startServerSpan(tracer Tracer, name string, traceparent string) *Span {
+	if tracer == nil {
+		return nil
+	}
+	parent, _ := ParseTraceparent(traceparent)
+	return startSpan(name, SpanKindServer, parent)
+}
+
+// exportSpan ends span with err, and exports it.
+func exportSpan(tracer Tracer, span *Span, err error) {
+	span.End = time.Now()
+	span.Err = err
+	tracer.ExportSpan(*span)
+}
+
+// withTrace returns a copy of ctx that carries the trace context of the incoming request
+// or message: the request's server span if there is one, or else the sender's traceparent.
+func withTrace(ctx context.Context, traceparent string, span *Span) context.Context {
+	if span != nil {
+		return ContextWithTrace(ctx, span.Trace)
+	}
+	if tc, err := ParseTraceparent(traceparent); err == nil {
+		return ContextWithTrace(ctx, tc)
+	}
+	return ctx
+}
@@ -0,0 +1,39 @@
+package birect_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestAttachments(t *testing.T) {
+	server, client := setupServerClient()
+
+	image := []byte{0x89, 0x50, 0x4e, 0x47, 0x00, 0xff}
+	type UploadParams struct{ Caption string }
+	server.HandleJSONReq("Upload", func(req *birect.JSONReq) (interface{}, error) {
+		attachments := req.Attachments()
+		assert(t, len(attachments) == 1 && attachments[0].Name == "image.png")
+		assert(t, bytes.Equal(attachments[0].Data, image))
+		req.AttachToResponse(birect.Attachment{Name: "thumbnail.png", Data: image[:2]})
+		return "ok", nil
+	})
+
+	var res string
+	resAttachments, err := client.SendJSONReqWithAttachments(context.Background(), "Upload", &res,
+		UploadParams{"Hi"}, []birect.Attachment{{Name: "image.png", Data: image}})
+	assert(t, err == nil, err)
+	assert(t, res == "ok")
+	assert(t, len(resAttachments) == 1 && resAttachments[0].Name == "thumbnail.png")
+	assert(t, bytes.Equal(resAttachments[0].Data, image[:2]))
+
+	received := make(chan string)
+	server.HandleJSONMessage("Photo", func(msg *birect.JSONMessage) {
+		received <- msg.Attachments()[0].Name
+	})
+	err = client.SendJSONMessage("Photo", nil, birect.Attachment{Name: "photo.jpg", Data: image})
+	assert(t, err == nil, err)
+	assert(t, waitForString(received) == "photo.jpg")
+}
@@ -0,0 +1,45 @@
+package birect_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestAuthenticate(t *testing.T) {
+	server, address := setupServer()
+	server.Authenticate = func(r *http.Request) (birect.Info, error) {
+		switch r.URL.Query().Get("token") {
+		case "secret":
+			return birect.Info{"UserID": "u1"}, nil
+		case "banned":
+			return nil, &birect.UpgradeError{Status: http.StatusForbidden, Message: "Banned"}
+		default:
+			return nil, errors.New("Bad token")
+		}
+	}
+	server.HandleJSONReq("TestAuthenticateWhoAmI", func(req *birect.JSONReq) (res interface{}, err error) {
+		return req.Conn.Info.GetString("UserID"), nil
+	})
+
+	client, err := birect.Connect(address + "?token=secret")
+	assert(t, err == nil, err)
+	var userID string
+	assert(t, client.SendJSONReq("TestAuthenticateWhoAmI", &userID, nil) == nil)
+	assert(t, userID == "u1")
+	client.Close()
+
+	_, err = birect.Connect(address + "?token=wrong")
+	assert(t, err != nil)
+
+	res, err := http.Get(address + "?token=banned")
+	assert(t, err == nil, err)
+	res.Body.Close()
+	assert(t, res.StatusCode == http.StatusForbidden, res.StatusCode)
+	res, err = http.Get(address)
+	assert(t, err == nil, err)
+	res.Body.Close()
+	assert(t, res.StatusCode == http.StatusUnauthorized, res.StatusCode)
+}
@@ -0,0 +1,51 @@
+package birect_test
+
+import (
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+func TestBroadcast(t *testing.T) {
+	server, address := setupServer()
+	client1, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client1.Close()
+	client2, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client2.Close()
+
+	type Join struct{ Name string }
+	server.HandleJSONReq("TestBroadcastJoin", func(req *birect.JSONReq) (res interface{}, err error) {
+		var par Join
+		req.ParseParams(&par)
+		req.Conn.Info.Set("Name", par.Name)
+		return nil, nil
+	})
+	received := make(chan string, 10)
+	for _, client := range []*birect.Client{client1, client2} {
+		client := client
+		client.HandleJSONMessage("TestBroadcast", func(msg *birect.JSONMessage) {
+			var par Join
+			msg.ParseValue(&par)
+			received <- par.Name
+		})
+		client.HandleProtoMessage("TestBroadcast", func(msg *birect.ProtoMessage) {
+			var par wire.Message
+			msg.ParseValue(&par)
+			received <- par.Name
+		})
+	}
+	assert(t, client1.SendJSONReq("TestBroadcastJoin", nil, Join{"One"}) == nil)
+	assert(t, client2.SendJSONReq("TestBroadcastJoin", nil, Join{"Two"}) == nil)
+
+	assert(t, server.BroadcastJSON("TestBroadcast", Join{"All"}, nil) == nil)
+	assert(t, waitForString(received) == "All" && waitForString(received) == "All")
+
+	onlyTwo := func(conn *birect.Conn) bool { return conn.Info.GetString("Name") == "Two" }
+	assert(t, server.BroadcastProto("TestBroadcast", &wire.Message{Name: "Proto"}, onlyTwo) == nil)
+	assert(t, server.BroadcastJSON("TestBroadcast", Join{"Two"}, onlyTwo) == nil)
+	assert(t, waitForString(received) == "Proto" && waitForString(received) == "Two")
+	assert(t, len(received) == 0)
+}
@@ -0,0 +1,32 @@
+package birect_test
+
+import (
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestConnRequest(t *testing.T) {
+	server, address := setupServer()
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+	assert(t, client.CurrentConn().Request() == nil)
+
+	type RequestInfo struct{ RemoteAddr, Path, Query, UserAgent string }
+	server.HandleJSONReq("TestConnRequest", func(req *birect.JSONReq) (res interface{}, err error) {
+		httpReq := req.Conn.Request()
+		_, cookieErr := httpReq.Cookie("session")
+		assert(t, cookieErr != nil)
+		return RequestInfo{httpReq.RemoteAddr, httpReq.URL.Path, httpReq.Query().Get("foo"), httpReq.Header.Get("User-Agent")}, nil
+	})
+	var res RequestInfo
+	assert(t, client.SendJSONReq("TestConnRequest", &res, nil) == nil)
+	assert(t, res.RemoteAddr != "" && res.Path != "" && res.Query == "" && res.UserAgent != "", res)
+
+	queryClient, err := birect.Connect(address + "?foo=bar")
+	assert(t, err == nil, err)
+	defer queryClient.Close()
+	assert(t, queryClient.SendJSONReq("TestConnRequest", &res, nil) == nil)
+	assert(t, res.Query == "bar", res)
+}
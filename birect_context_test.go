@@ -0,0 +1,68 @@
+package birect_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	server, client := setupServerClient()
+
+	handlerErr := make(chan error, 1)
+	clientDone := make(chan bool)
+	server.HandleJSONReq("Slow", func(req *birect.JSONReq) (res interface{}, err error) {
+		_, hasDeadline := req.Context().Deadline()
+		assert(t, hasDeadline)
+		<-req.Context().Done()
+		handlerErr <- req.Context().Err()
+		<-clientDone
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.SendJSONReqContext(ctx, "Slow", nil, nil)
+	close(clientDone)
+	assert(t, err == context.DeadlineExceeded, err)
+	assert(t, <-handlerErr == context.DeadlineExceeded)
+}
+
+func TestRequestCancel(t *testing.T) {
+	server, client := setupServerClient()
+
+	started := make(chan bool)
+	handlerErr := make(chan error, 1)
+	server.HandleJSONReq("Slow", func(req *birect.JSONReq) (res interface{}, err error) {
+		_, hasDeadline := req.Context().Deadline()
+		assert(t, !hasDeadline)
+		started <- true
+		<-req.Context().Done()
+		handlerErr <- req.Context().Err()
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	err := client.SendJSONReqContext(ctx, "Slow", nil, nil)
+	assert(t, err == context.Canceled, err)
+	select {
+	case err = <-handlerErr:
+		assert(t, err == context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("Handler context was not cancelled")
+	}
+
+	// The conn keeps working after a cancelled request
+	server.HandleJSONReq("Fast", func(req *birect.JSONReq) (res interface{}, err error) {
+		return "ok", nil
+	})
+	var res string
+	err = client.SendJSONReq("Fast", &res, nil)
+	assert(t, err == nil && res == "ok")
+}
@@ -7,15 +7,13 @@ import (
 	"net"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
 	"github.com/marcuswestin/go-errs"
 )
 
-type ResponseError struct {
-	RetryIn int
-}
-
 func TestApplicationError(t *testing.T) {
 	var err error
 	server, client := setupServerClient()
@@ -52,7 +50,50 @@ func TestDefaultErrorMessage(t *testing.T) {
 	})
 	err := client.SendJSONReq("TestDefaultErrorMessage", nil, nil)
 	assert(t, err != nil)
-	// assert(t, err.Error() == birect.DefaultPublicErrorMessage)
+	assert(t, err.Error() == birect.DefaultPublicErrorMessage)
+}
+
+func TestResponseError(t *testing.T) {
+	server, client := setupServerClient()
+
+	type QuotaDetails struct{ Remaining int }
+	server.HandleJSONReq("TestResponseError", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, &birect.ResponseError{
+			Message:    "Slow down",
+			Code:       "QUOTA_EXCEEDED",
+			Retryable:  true,
+			RetryAfter: 3 * time.Second,
+			Details:    QuotaDetails{Remaining: 0},
+		}
+	})
+	err := client.SendJSONReq("TestResponseError", nil, nil)
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok, err)
+	assert(t, resErr.Error() == "Slow down")
+	assert(t, resErr.Code == "QUOTA_EXCEEDED")
+	assert(t, resErr.Retryable && resErr.RetryAfter == 3*time.Second)
+	details := QuotaDetails{Remaining: -1}
+	assert(t, resErr.HasDetails() && resErr.ParseDetails(&details) == nil)
+	assert(t, details.Remaining == 0)
+
+	server.HandleProtoReq("TestProtoResponseError", func(req *birect.ProtoReq) (res birect.Proto, err error) {
+		return nil, &birect.ResponseError{Code: "NOT_FOUND", Details: &wire.Message{Name: "missing"}}
+	})
+	err = client.SendProtoReq("TestProtoResponseError", &wire.Message{}, &wire.Message{})
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok, err)
+	assert(t, resErr.Code == "NOT_FOUND" && !resErr.Retryable)
+	assert(t, resErr.Error() == birect.DefaultPublicErrorMessage)
+	var msg wire.Message
+	assert(t, resErr.ParseDetails(&msg) == nil && msg.Name == "missing")
+
+	// Wrapped ResponseErrors are sent as ResponseErrors too
+	server.HandleJSONReq("TestWrappedResponseError", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, fmt.Errorf("Checking quota: %w", &birect.ResponseError{Message: "Slow down", Code: "QUOTA_EXCEEDED"})
+	})
+	err = client.SendJSONReq("TestWrappedResponseError", nil, nil)
+	assert(t, errors.As(err, &resErr), err)
+	assert(t, resErr.Code == "QUOTA_EXCEEDED" && resErr.Error() == "Slow down", resErr)
 }
 
 // Misc utils
@@ -60,19 +101,25 @@ func TestDefaultErrorMessage(t *testing.T) {
 var lastPort = 18000
 
 func setupServerClient() (*birect.Handler, *birect.Client) {
-	lastPort += 1
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", lastPort))
+	server, address := setupServer()
+	client, err := birect.Connect(address)
 	if err != nil {
 		panic(err)
 	}
-	go http.Serve(listener, nil)
+	return server, client
+}
 
-	server := birect.UpgradeRequests(fmt.Sprintf("/birect/upgrade/%d", lastPort))
-	client, err := birect.Connect(fmt.Sprintf("http://localhost:%d/birect/upgrade/%d", lastPort, lastPort))
+// setupServer returns a server on a new port, along with the address to connect to it.
+func setupServer() (*birect.Handler, string) {
+	lastPort += 1
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", lastPort))
 	if err != nil {
 		panic(err)
 	}
-	return server, client
+	go http.Serve(listener, nil)
+
+	path := fmt.Sprintf("/birect/upgrade/%d", lastPort)
+	return birect.UpgradeRequests(path), fmt.Sprintf("http://localhost:%d%s", lastPort, path)
 }
 
 func assert(t *testing.T, ok bool, msg ...interface{}) {
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package birect_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestGenericJSON(t *testing.T) {
+	server, client := setupServerClient()
+
+	type EchoParams struct{ Text string }
+	type EchoResponse struct{ Text string }
+	birect.HandleJSON(server, "TestGenericEcho", func(ctx context.Context, conn *birect.Conn, par EchoParams) (EchoResponse, error) {
+		return EchoResponse{"Re: " + par.Text}, nil
+	})
+	res, err := birect.CallJSON[EchoParams, EchoResponse](context.Background(), client, "TestGenericEcho", EchoParams{"Hi"})
+	assert(t, err == nil, err)
+	assert(t, res.Text == "Re: Hi")
+
+	// Mismatched params get an error response rather than a panic
+	_, err = birect.CallJSON[[]int, EchoResponse](context.Background(), client, "TestGenericEcho", []int{1})
+	assert(t, err != nil)
+
+	// Calls work on a Conn too
+	birect.HandleJSON(client, "TestGenericSum", func(ctx context.Context, conn *birect.Conn, nums []int) (sum int, err error) {
+		for _, num := range nums {
+			sum += num
+		}
+		return
+	})
+	sum, err := birect.CallJSON[[]int, int](context.Background(), server.Conns()[0], "TestGenericSum", []int{1, 2, 3})
+	assert(t, err == nil && sum == 6, err)
+}
@@ -0,0 +1,99 @@
+package birect_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestHeartbeat(t *testing.T) {
+	server, address := setupServer()
+	disconnected := make(chan *birect.Conn, 10)
+	server.DisconnectHandler = func(conn *birect.Conn) { disconnected <- conn }
+	server.SetHeartbeat(birect.Heartbeat{Interval: 20 * time.Millisecond, Timeout: 100 * time.Millisecond})
+	unblock := make(chan bool)
+	defer close(unblock)
+	server.HandleJSONReq("TestHeartbeat", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+	server.HandleJSONMessage("TestHeartbeatBlockClient", func(msg *birect.JSONMessage) {
+		msg.Conn.SendJSONMessage("TestHeartbeatBlock", nil)
+	})
+
+	// Pongs keep quiet connections alive, while clients that stop answering pings get disconnected.
+	// Messages are handled on the connection's read loop, so a blocking message handler stops all reads.
+	quiet, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer quiet.Close()
+	blocked, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer blocked.Close()
+	blocked.HandleJSONMessage("TestHeartbeatBlock", func(msg *birect.JSONMessage) {
+		<-unblock
+	})
+	assert(t, blocked.SendJSONMessage("TestHeartbeatBlockClient", nil) == nil)
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("Expected blocked client to get disconnected")
+	}
+	// The quiet client connected first, and has sent nothing but pongs since
+	assert(t, len(disconnected) == 0)
+	assert(t, quiet.SendJSONReq("TestHeartbeat", nil, nil) == nil)
+	assert(t, len(disconnected) == 0)
+}
+
+func TestClientHeartbeat(t *testing.T) {
+	server, client := setupServerClient()
+	unblock := make(chan bool)
+	defer close(unblock)
+	server.HandleJSONMessage("TestClientHeartbeatBlock", func(msg *birect.JSONMessage) {
+		<-unblock
+	})
+
+	// Clients detect unresponsive servers the same way, and reconnect
+	clientDisconnected := make(chan bool, 1)
+	client.OnDisconnect = func(*birect.Conn) { clientDisconnected <- true }
+	client.SetHeartbeat(birect.Heartbeat{Interval: 20 * time.Millisecond, Timeout: 100 * time.Millisecond})
+	assert(t, client.SendJSONMessage("TestClientHeartbeatBlock", nil) == nil)
+	select {
+	case <-clientDisconnected:
+	case <-time.After(time.Second):
+		t.Fatal("Expected client to detect the blocked server")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	server, address := setupServer()
+	disconnected := make(chan *birect.Conn, 10)
+	server.DisconnectHandler = func(conn *birect.Conn) { disconnected <- conn }
+	server.SetHeartbeat(birect.Heartbeat{IdleTimeout: 100 * time.Millisecond})
+	server.HandleJSONReq("TestIdleTimeout", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+	server.HandleJSONReq("TestIdleTimeoutSlow", func(req *birect.JSONReq) (res interface{}, err error) {
+		time.Sleep(300 * time.Millisecond)
+		return nil, nil
+	})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	// Requests count as activity
+	for i := 0; i < 8; i++ {
+		assert(t, client.SendJSONReq("TestIdleTimeout", nil, nil) == nil)
+		time.Sleep(30 * time.Millisecond)
+	}
+	assert(t, len(disconnected) == 0)
+
+	// So do requests that are being handled, for however long they take
+	assert(t, client.SendJSONReq("TestIdleTimeoutSlow", nil, nil) == nil)
+	assert(t, len(disconnected) == 0)
+
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("Expected idle client to get disconnected")
+	}
+}
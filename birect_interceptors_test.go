@@ -0,0 +1,58 @@
+package birect_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	server, client := setupServerClient()
+
+	type Echo struct{ Text string }
+	server.HandleJSONReq("TestInterceptorsWhoAmI", func(req *birect.JSONReq) (res interface{}, err error) {
+		md := birect.MetadataFromContext(req.Context())
+		return Echo{md.Get("Authorization") + " " + md.Get("RequestTag")}, nil
+	})
+	var attempts int32
+	server.HandleJSONReq("TestInterceptorsFlaky", func(req *birect.JSONReq) (res interface{}, err error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, &birect.ResponseError{Code: "UNAVAILABLE", Retryable: true}
+		}
+		return Echo{"Finally"}, nil
+	})
+
+	var logged []string
+	client.Intercept(func(req *birect.OutgoingReq, invoke birect.InvokeFunc) error {
+		req.Metadata["Authorization"] = "Token"
+		err := invoke()
+		if echo, ok := req.ResValPtr.(*Echo); ok && err == nil {
+			logged = append(logged, echo.Text)
+		}
+		return err
+	})
+	client.Intercept(func(req *birect.OutgoingReq, invoke birect.InvokeFunc) (err error) {
+		if req.Name == "TestInterceptorsCached" {
+			*req.ResValPtr.(*Echo) = Echo{"From cache"}
+			return nil
+		}
+		for {
+			err = invoke()
+			if resErr, ok := err.(*birect.ResponseError); !ok || !resErr.Retryable {
+				return err
+			}
+		}
+	})
+
+	var res Echo
+	ctx := birect.ContextWithMetadata(context.Background(), birect.Metadata{"RequestTag": "Tag"})
+	assert(t, client.SendJSONReqContext(ctx, "TestInterceptorsWhoAmI", &res, nil) == nil)
+	assert(t, res.Text == "Token Tag", res)
+	assert(t, client.SendJSONReq("TestInterceptorsFlaky", &res, nil) == nil)
+	assert(t, res.Text == "Finally" && atomic.LoadInt32(&attempts) == 3)
+	assert(t, client.SendJSONReq("TestInterceptorsCached", &res, nil) == nil)
+	assert(t, res.Text == "From cache")
+	assert(t, len(logged) == 3 && logged[0] == "Token Tag" && logged[1] == "Finally" && logged[2] == "From cache", logged)
+}
@@ -0,0 +1,84 @@
+package birect_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestLimits(t *testing.T) {
+	server, address := setupServer()
+	server.SetLimits(birect.Limits{MaxInFlightPerConn: 1, QueueSize: 1})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	started := make(chan bool, 10)
+	unblock := make(chan bool)
+	server.HandleJSONReq("TestLimits", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-unblock
+		return nil, nil
+	})
+	results := make(chan error, 3)
+	send := func() { results <- client.SendJSONReq("TestLimits", nil, nil) }
+
+	// The first request runs, the second waits in the queue, and the third gets rejected
+	go send()
+	<-started
+	go send()
+	go send()
+	err = <-results
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.BusyCode && resErr.Retryable, err)
+	assert(t, len(started) == 0)
+
+	close(unblock)
+	assert(t, <-results == nil)
+	assert(t, <-results == nil)
+	assert(t, len(started) == 1)
+}
+
+func TestLimitsStreams(t *testing.T) {
+	server, address := setupServer()
+	server.SetLimits(birect.Limits{MaxInFlight: 1})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	started := make(chan bool, 10)
+	server.HandleStream("TestLimitsStreams", func(stream *birect.Stream) error {
+		started <- true
+		var value string
+		for stream.Recv(&value) == nil {
+		}
+		return nil
+	})
+	server.HandleJSONReq("TestLimitsStreams", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+
+	// An open stream takes up the handler slot, so other streams and requests get rejected
+	stream, err := client.OpenStream("TestLimitsStreams")
+	assert(t, err == nil, err)
+	<-started
+	busyStream, err := client.OpenStream("TestLimitsStreams")
+	assert(t, err == nil, err)
+	var value string
+	err = busyStream.Recv(&value)
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.BusyCode, err)
+	err = client.SendJSONReq("TestLimitsStreams", nil, nil)
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.BusyCode, err)
+	assert(t, len(started) == 0)
+
+	// Once the stream's handler is done, its slot is free again
+	assert(t, stream.CloseSend() == nil)
+	assert(t, stream.Recv(&value) == io.EOF)
+	for i := 0; i < 100 && err != nil; i++ {
+		err = client.SendJSONReq("TestLimitsStreams", nil, nil)
+	}
+	assert(t, err == nil, err)
+}
@@ -0,0 +1,54 @@
+package birect_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+func TestJSONMessages(t *testing.T) {
+	server, client := setupServerClient()
+
+	type Notification struct{ Text string }
+	received := make(chan string)
+	client.HandleJSONMessage("Notify", func(msg *birect.JSONMessage) {
+		var n Notification
+		msg.ParseValue(&n)
+		received <- n.Text
+	})
+	server.HandleJSONMessage("Notify", func(msg *birect.JSONMessage) {
+		var n Notification
+		msg.ParseValue(&n)
+		msg.Conn.SendJSONMessage("Notify", Notification{"Re: " + n.Text})
+	})
+
+	err := client.SendJSONMessage("Notify", Notification{"Hi"})
+	assert(t, err == nil)
+	assert(t, waitForString(received) == "Re: Hi")
+}
+
+func TestProtoMessages(t *testing.T) {
+	server, client := setupServerClient()
+
+	received := make(chan string)
+	server.HandleProtoMessage("Notify", func(msg *birect.ProtoMessage) {
+		var n wire.Message
+		msg.ParseValue(&n)
+		received <- n.Name
+	})
+
+	err := client.SendProtoMessage("Notify", &wire.Message{Name: "Hi"})
+	assert(t, err == nil)
+	assert(t, waitForString(received) == "Hi")
+}
+
+func waitForString(c chan string) string {
+	select {
+	case str := <-c:
+		return str
+	case <-time.After(time.Second):
+		return "<timeout>"
+	}
+}
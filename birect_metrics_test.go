@@ -0,0 +1,71 @@
+package birect_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestMetrics(t *testing.T) {
+	server, address := setupServer()
+	uncounted, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	metrics := birect.NewMetrics()
+	server.SetMetrics(metrics)
+	server.HandleJSONReq("TestMetrics", func(req *birect.JSONReq) (res interface{}, err error) {
+		return "Hello", nil
+	})
+	server.HandleJSONReq("TestMetricsError", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, &birect.ResponseError{Code: "NOPE"}
+	})
+	server.HandleTextReq("TestMetrics", func(req *birect.TextReq) (res string, err error) {
+		return "Hi", nil
+	})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+
+	var res string
+	assert(t, client.SendJSONReq("TestMetrics", &res, "Hi") == nil)
+	assert(t, client.SendJSONReq("TestMetrics", &res, "Hi") == nil)
+	assert(t, client.SendJSONReq("TestMetricsError", nil, nil) != nil)
+	assert(t, client.SendTextReq("TestMetrics", &res, "Hello") == nil)
+	assert(t, client.SendJSONReq("TestMetricsMissing", nil, nil) != nil)
+	assert(t, client.SendTextReq("TestMetricsError", &res, "Hello") != nil)
+
+	text := scrapeMetrics(metrics)
+	for _, line := range []string{
+		"birect_connections 1",
+		"birect_connects_total 1",
+		"birect_disconnects_total 0",
+		`birect_requests_total{name="TestMetrics",type="JSON"} 2`,
+		`birect_requests_total{name="TestMetrics",type="Text"} 1`,
+		`birect_request_errors_total{name="TestMetricsError",type="JSON",code="NOPE"} 1`,
+		`birect_request_duration_seconds_count{name="TestMetrics",type="JSON"} 2`,
+		`birect_request_size_bytes_bucket{name="TestMetrics",type="JSON",le="64"} 2`,
+		`birect_request_size_bytes_sum{name="TestMetrics",type="JSON"} 8`,
+		`birect_response_size_bytes_sum{name="TestMetrics",type="Text"} 2`,
+		// Requests without a handler don't get their own labels
+		`birect_requests_total{name="unknown",type="JSON"} 1`,
+		`birect_requests_total{name="unknown",type="Text"} 1`,
+	} {
+		assert(t, strings.Contains(text, line+"\n"), line, text)
+	}
+	assert(t, !strings.Contains(text, `birect_request_errors_total{name="TestMetrics"`), text)
+	assert(t, !strings.Contains(text, "TestMetricsMissing"), text)
+
+	// Connections that were opened before SetMetrics don't get counted when they close either
+	uncounted.Close()
+	client.Close()
+	assert(t, waitForConnCount(server, 0))
+	text = scrapeMetrics(metrics)
+	assert(t, strings.Contains(text, "birect_connections 0\n"), text)
+	assert(t, strings.Contains(text, "birect_disconnects_total 1\n"), text)
+}
+
+func scrapeMetrics(metrics *birect.Metrics) string {
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	return recorder.Body.String()
+}
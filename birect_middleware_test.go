@@ -0,0 +1,117 @@
+package birect_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+func TestMiddleware(t *testing.T) {
+	server, client := setupServerClient()
+
+	var logMutex sync.Mutex
+	var log []string
+	server.Use(func(req *birect.HandlerReq, next birect.NextFunc) (interface{}, error) {
+		res, err := next()
+		logMutex.Lock()
+		defer logMutex.Unlock()
+		log = append(log, req.Name)
+		if err != nil {
+			log = append(log, "error")
+		}
+		return res, err
+	})
+	server.Use(func(req *birect.HandlerReq, next birect.NextFunc) (interface{}, error) {
+		if req.Name == "TestMiddlewareSecret" && req.Info().GetString("UserID") == "" {
+			return nil, &birect.ResponseError{Code: "UNAUTHENTICATED"}
+		}
+		return next()
+	})
+
+	type Login struct{ UserID string }
+	server.HandleJSONReq("TestMiddlewareLogin", func(req *birect.JSONReq) (res interface{}, err error) {
+		var par Login
+		req.ParseParams(&par)
+		req.Conn.Info.Set("UserID", par.UserID)
+		return par, nil
+	})
+	server.HandleProtoReq("TestMiddlewareSecret", func(req *birect.ProtoReq) (res birect.Proto, err error) {
+		return &wire.Message{Name: "Secret for " + req.Info.GetString("UserID")}, nil
+	})
+	server.HandleJSONReq("TestMiddlewarePanic", func(req *birect.JSONReq) (res interface{}, err error) {
+		panic("Oops")
+	})
+
+	var res wire.Message
+	err := client.SendProtoReq("TestMiddlewareSecret", &res, &wire.Message{})
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == "UNAUTHENTICATED", err)
+
+	var login Login
+	assert(t, client.SendJSONReq("TestMiddlewareLogin", &login, Login{"u1"}) == nil)
+	assert(t, client.SendProtoReq("TestMiddlewareSecret", &res, &wire.Message{}) == nil)
+	assert(t, res.Name == "Secret for u1")
+
+	// Handler panics reach middleware as errors
+	assert(t, client.SendJSONReq("TestMiddlewarePanic", nil, nil) != nil)
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	expected := []string{"TestMiddlewareSecret", "error", "TestMiddlewareLogin", "TestMiddlewareSecret", "TestMiddlewarePanic", "error"}
+	assert(t, len(log) == len(expected), log)
+	for i := range expected {
+		assert(t, log[i] == expected[i], log)
+	}
+}
+
+func TestMiddlewareStreams(t *testing.T) {
+	server, client := setupServerClient()
+
+	server.Use(func(req *birect.HandlerReq, next birect.NextFunc) (interface{}, error) {
+		if req.Name != "TestMiddlewareStreamsLogin" && req.Info().GetString("UserID") == "" {
+			return nil, &birect.ResponseError{Code: "UNAUTHENTICATED"}
+		}
+		return next()
+	})
+	handled := make(chan string, 2)
+	server.HandleStreamJSONReq("TestMiddlewareStreamReq", func(req *birect.StreamJSONReq) error {
+		handled <- "TestMiddlewareStreamReq"
+		return nil
+	})
+	server.HandleStream("TestMiddlewareStream", func(stream *birect.Stream) error {
+		handled <- "TestMiddlewareStream"
+		return nil
+	})
+
+	reader, err := client.SendStreamJSONReq(context.Background(), "TestMiddlewareStreamReq", nil)
+	assert(t, err == nil, err)
+	var value string
+	err = reader.Recv(&value)
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == "UNAUTHENTICATED", err)
+
+	stream, err := client.OpenStream("TestMiddlewareStream")
+	assert(t, err == nil, err)
+	err = stream.Recv(&value)
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == "UNAUTHENTICATED", err)
+	assert(t, len(handled) == 0)
+
+	// Once the middleware lets them through, the handlers run
+	server.HandleJSONReq("TestMiddlewareStreamsLogin", func(req *birect.JSONReq) (interface{}, error) {
+		req.Conn.Info.Set("UserID", "u1")
+		return nil, nil
+	})
+	assert(t, client.SendJSONReq("TestMiddlewareStreamsLogin", nil, nil) == nil)
+	reader, err = client.SendStreamJSONReq(context.Background(), "TestMiddlewareStreamReq", nil)
+	assert(t, err == nil, err)
+	assert(t, reader.Recv(&value) == io.EOF)
+	stream, err = client.OpenStream("TestMiddlewareStream")
+	assert(t, err == nil, err)
+	assert(t, stream.Recv(&value) == io.EOF)
+	assert(t, <-handled == "TestMiddlewareStreamReq" && <-handled == "TestMiddlewareStream")
+}
@@ -0,0 +1,33 @@
+package birect
+
+import (
+	"testing"
+
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// Duplicate responses can not be sent through the public API, so pendingReqs is tested directly.
+func TestPendingReqsDuplicateResponse(t *testing.T) {
+	pending := newPendingReqs()
+	resChan, err := pending.add(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pending.resolve(&wire.Response{ReqId: 1}) {
+		t.Fatal("Expected response to be delivered")
+	}
+	if pending.resolve(&wire.Response{ReqId: 1}) {
+		t.Fatal("Expected duplicate response to be dropped")
+	}
+	if pending.resolve(&wire.Response{ReqId: 2}) {
+		t.Fatal("Expected response for unknown request to be dropped")
+	}
+	if res := <-resChan; res.ReqId != 1 {
+		t.Fatal("Unexpected response", res)
+	}
+
+	pending.close()
+	if _, err := pending.add(3); err != ErrConnClosed {
+		t.Fatal("Expected ErrConnClosed after close", err)
+	}
+}
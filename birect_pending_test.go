@@ -0,0 +1,68 @@
+package birect_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestPendingReqsFailOnClose(t *testing.T) {
+	server, client := setupServerClient()
+	started := make(chan bool, 3)
+	release := make(chan bool)
+	defer close(release)
+	server.HandleJSONReq("TestPendingReqsFailOnClose", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-release
+		return nil, nil
+	})
+
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { results <- client.SendJSONReq("TestPendingReqsFailOnClose", nil, nil) }()
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	assert(t, client.Close() == nil)
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-results:
+			assert(t, err == birect.ErrConnClosed, err)
+		case <-time.After(time.Second):
+			t.Fatal("Expected pending request to fail")
+		}
+	}
+
+	// Requests sent after the conn closed fail right away
+	assert(t, client.SendJSONReq("TestPendingReqsFailOnClose", nil, nil) == birect.ErrConnClosed)
+}
+
+func TestStrayResponses(t *testing.T) {
+	server, client := setupServerClient()
+	responded := make(chan bool)
+	release := make(chan bool)
+	server.HandleJSONReq("TestStrayResponses", func(req *birect.JSONReq) (res interface{}, err error) {
+		<-release
+		defer func() { responded <- true }()
+		return "Late", nil
+	})
+	server.HandleJSONReq("TestStrayResponsesFast", func(req *birect.JSONReq) (res interface{}, err error) {
+		return "Fast", nil
+	})
+
+	// The response arrives after the requester gave up, and gets dropped
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var res string
+	err := client.SendJSONReqContext(ctx, "TestStrayResponses", &res, nil)
+	assert(t, err == context.DeadlineExceeded, err)
+	close(release)
+	<-responded
+
+	// The conn keeps working, and the stray response is not mistaken for a later one
+	assert(t, client.SendJSONReq("TestStrayResponsesFast", &res, nil) == nil)
+	assert(t, res == "Fast", res)
+}
@@ -0,0 +1,53 @@
+package birect
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// The order in which requests get queued can not be controlled through the public API,
+// so the offline queue is tested directly.
+func TestOfflineQueueOrder(t *testing.T) {
+	const numReqs = 10
+	conn := &Conn{}
+	client := &Client{OfflineQueue: OfflineQueue{Size: numReqs}, connMutex: &sync.Mutex{}, closedChan: make(chan struct{}), conn: conn}
+
+	turns := make(chan int)
+	for i := 0; i < numReqs; i++ {
+		req, _, err := client.enqueue()
+		if req == nil || err != nil {
+			t.Fatal("Expected request to be queued", err)
+		}
+		go func(i int, req *queuedReq) {
+			reqConn, sent, err := client.awaitTurn(context.Background(), req)
+			if reqConn != conn || err != nil {
+				t.Error("Expected request to be handed the conn", err)
+			}
+			turns <- i
+			sent()
+		}(i, req)
+	}
+	if _, _, err := client.enqueue(); err != ErrOfflineQueueFull {
+		t.Fatal("Expected ErrOfflineQueueFull", err)
+	}
+
+	// Each request gets its turn once the one before it has been sent
+	flushed := make(chan bool)
+	go func() {
+		client.flushQueue(conn)
+		flushed <- true
+	}()
+	for i := 0; i < numReqs; i++ {
+		if turn := <-turns; turn != i {
+			t.Fatal("Expected request", i, "to be sent, but got", turn)
+		}
+	}
+
+	// Once flushed, the client sends requests right away
+	<-flushed
+	req, reqConn, err := client.enqueue()
+	if req != nil || reqConn != conn || err != nil {
+		t.Fatal("Expected request to be sent right away", err)
+	}
+}
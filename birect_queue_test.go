@@ -0,0 +1,79 @@
+package birect_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	server, client := setupServerClient()
+	disconnected := make(chan *birect.Conn, 1)
+	client.Backoff = birect.Backoff{MinDelay: 300 * time.Millisecond, MaxDelay: time.Second, Factor: 2}
+	client.OfflineQueue = birect.OfflineQueue{Size: 3}
+	client.OnDisconnect = func(conn *birect.Conn) { disconnected <- conn }
+
+	type Echo struct{ Num int }
+	var handledMutex sync.Mutex
+	handled := map[int]bool{}
+	server.HandleJSONReq("TestOfflineQueue", func(req *birect.JSONReq) (res interface{}, err error) {
+		var par Echo
+		req.ParseParams(&par)
+		handledMutex.Lock()
+		handled[par.Num] = true
+		handledMutex.Unlock()
+		return par, nil
+	})
+	var res Echo
+	assert(t, client.SendJSONReq("TestOfflineQueue", &res, Echo{0}) == nil)
+	for _, conn := range server.Conns() {
+		conn.Close()
+	}
+	<-disconnected
+
+	// Queued requests get sent once the client reconnects. Their order, and the queue's
+	// size limit, are tested in TestOfflineQueueOrder.
+	errChan := make(chan error, 3)
+	for i := 1; i <= 3; i++ {
+		go func(num int) {
+			var res Echo
+			err := client.SendJSONReq("TestOfflineQueue", &res, Echo{num})
+			if err == nil && res.Num != num {
+				err = birect.NewError(nil, "Bad response")
+			}
+			errChan <- err
+		}(i)
+	}
+	for i := 1; i <= 3; i++ {
+		err := <-errChan
+		assert(t, err == nil, err)
+	}
+	handledMutex.Lock()
+	defer handledMutex.Unlock()
+	assert(t, len(handled) == 4 && handled[1] && handled[2] && handled[3], handled)
+}
+
+func TestOfflineQueueExpiry(t *testing.T) {
+	server, client := setupServerClient()
+	disconnected := make(chan *birect.Conn, 1)
+	client.Backoff = birect.Backoff{MinDelay: 300 * time.Millisecond, MaxDelay: time.Second, Factor: 2}
+	client.OfflineQueue = birect.OfflineQueue{Size: 10, MaxAge: 20 * time.Millisecond}
+	client.OnDisconnect = func(conn *birect.Conn) { disconnected <- conn }
+
+	server.HandleJSONReq("TestOfflineQueueExpiry", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+	assert(t, client.SendJSONReq("TestOfflineQueueExpiry", nil, nil) == nil)
+	for _, conn := range server.Conns() {
+		conn.Close()
+	}
+	<-disconnected
+	assert(t, client.SendJSONReq("TestOfflineQueueExpiry", nil, nil) == birect.ErrRequestExpired)
+
+	// Without an offline queue, requests fail right away while reconnecting
+	client.OfflineQueue = birect.OfflineQueue{}
+	assert(t, client.SendJSONReq("TestOfflineQueueExpiry", nil, nil) == birect.ErrConnClosed)
+	client.Close()
+}
@@ -0,0 +1,32 @@
+package birect
+
+import (
+	"testing"
+	"time"
+)
+
+// Buckets can not be inspected through the public API, so dropping the
+// buckets of closed connections is tested directly.
+func TestRateLimitRemoveConn(t *testing.T) {
+	limits := newRateLimits()
+	limits.add([]*rateLimiter{
+		newRateLimiter(RateLimit{Rate: 1, Burst: 1, By: []RateLimitKey{PerConn, PerName}}),
+		newRateLimiter(RateLimit{Rate: 1, Burst: 2, By: []RateLimitKey{PerName}}),
+	})
+	conn1, conn2 := &Conn{id: 1}, &Conn{id: 2}
+	for _, req := range []*HandlerReq{{"A", conn1, nil}, {"B", conn1, nil}, {"A", conn2, nil}} {
+		limits.take(req)
+	}
+	perConn, perName := limits.limiters[0], limits.limiters[1]
+	if len(perConn.buckets) != 3 || len(perName.buckets) != 2 {
+		t.Fatal("Expected a bucket per conn and name", len(perConn.buckets), len(perName.buckets))
+	}
+
+	limits.removeConn(conn1)
+	if len(perConn.buckets) != 1 || len(perName.buckets) != 2 {
+		t.Fatal("Expected only the closed conn's buckets to be dropped", len(perConn.buckets), len(perName.buckets))
+	}
+	if _, ok := perConn.take(perConn.bucketKey(&HandlerReq{"A", conn2, nil}), conn2.id, time.Now()); ok {
+		t.Fatal("Expected the open conn to keep its bucket")
+	}
+}
@@ -0,0 +1,113 @@
+package birect_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestRateLimit(t *testing.T) {
+	server, address := setupServer()
+	server.Authenticate = func(r *http.Request) (birect.Info, error) {
+		return birect.Info{"UserID": r.URL.Query().Get("user")}, nil
+	}
+	server.RateLimit(
+		birect.RateLimit{Rate: 0.5, Burst: 3, By: []birect.RateLimitKey{birect.PerInfo("UserID")}},
+		birect.RateLimit{Rate: 0.5, Burst: 1, Names: []string{"TestRateLimitCostly"}, By: []birect.RateLimitKey{birect.PerConn}},
+	)
+	server.HandleJSONReq("TestRateLimit", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+	server.HandleJSONReq("TestRateLimitCostly", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+
+	alice1, err := birect.Connect(address + "?user=alice")
+	assert(t, err == nil, err)
+	defer alice1.Close()
+	alice2, err := birect.Connect(address + "?user=alice")
+	assert(t, err == nil, err)
+	defer alice2.Close()
+	bob, err := birect.Connect(address + "?user=bob")
+	assert(t, err == nil, err)
+	defer bob.Close()
+
+	// Alice's connections share a bucket
+	assert(t, alice1.SendJSONReq("TestRateLimit", nil, nil) == nil)
+	assert(t, alice2.SendJSONReq("TestRateLimit", nil, nil) == nil)
+	assert(t, alice2.SendJSONReq("TestRateLimit", nil, nil) == nil)
+	err = alice1.SendJSONReq("TestRateLimit", nil, nil)
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.RateLimitedCode && resErr.Retryable, err)
+	assert(t, resErr.RetryAfter > time.Second && resErr.RetryAfter <= 2*time.Second, resErr.RetryAfter)
+
+	// Costly requests are limited per connection as well. Rejected requests don't take a token from Bob's bucket
+	assert(t, bob.SendJSONReq("TestRateLimitCostly", nil, nil) == nil)
+	err = bob.SendJSONReq("TestRateLimitCostly", nil, nil)
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.RateLimitedCode, err)
+	assert(t, bob.SendJSONReq("TestRateLimit", nil, nil) == nil)
+	assert(t, bob.SendJSONReq("TestRateLimit", nil, nil) == nil)
+	err = bob.SendJSONReq("TestRateLimit", nil, nil)
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.RateLimitedCode, err)
+}
+
+func TestRateLimitSlots(t *testing.T) {
+	server, address := setupServer()
+	server.SetLimits(birect.Limits{MaxInFlightPerConn: 1})
+	server.RateLimit(
+		birect.RateLimit{Rate: 0.001, Burst: 3, By: []birect.RateLimitKey{birect.PerConn}},
+		birect.RateLimit{Rate: 0.001, Burst: 1, Names: []string{"TestRateLimitSlots"}, By: []birect.RateLimitKey{birect.PerConn}},
+	)
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	started := make(chan bool, 10)
+	unblock := make(chan bool)
+	server.HandleJSONReq("TestRateLimitSlotsBlock", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-unblock
+		return nil, nil
+	})
+	server.HandleJSONReq("TestRateLimitSlots", func(req *birect.JSONReq) (res interface{}, err error) {
+		return nil, nil
+	})
+	sendErrCode := func(name string) string {
+		resErr, _ := client.SendJSONReq(name, nil, nil).(*birect.ResponseError)
+		if resErr == nil {
+			return ""
+		}
+		return resErr.Code
+	}
+
+	// Requests over a rate limit get rejected as rate limited even while the handler slot is taken
+	assert(t, sendErrCode("TestRateLimitSlots") == "")
+	results := make(chan error, 1)
+	go func() { results <- client.SendJSONReq("TestRateLimitSlotsBlock", nil, nil) }()
+	<-started
+	assert(t, sendErrCode("TestRateLimitSlots") == birect.RateLimitedCode)
+
+	// Requests that get rejected because the server is busy get their tokens back
+	assert(t, sendErrCode("TestRateLimitSlotsBlock") == birect.BusyCode)
+	close(unblock)
+	assert(t, <-results == nil)
+	assert(t, sendErrCode("TestRateLimitSlotsBlock") == "")
+	assert(t, sendErrCode("TestRateLimitSlotsBlock") == birect.RateLimitedCode)
+	assert(t, len(started) == 1)
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	server, _ := setupServer()
+	for _, rateLimit := range []birect.RateLimit{{Rate: 0, Burst: 1}, {Rate: 1, Burst: 0}, {Rate: -1, Burst: 1}} {
+		func() {
+			defer func() {
+				assert(t, recover() != nil, rateLimit)
+			}()
+			server.RateLimit(rateLimit)
+		}()
+	}
+}
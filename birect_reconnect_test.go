@@ -0,0 +1,77 @@
+package birect_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestClientReconnect(t *testing.T) {
+	server, client := setupServerClient()
+	disconnected := make(chan *birect.Conn, 1)
+	reconnected := make(chan *birect.Conn, 1)
+	client.Backoff = birect.Backoff{MinDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Factor: 2}
+	client.OnDisconnect = func(conn *birect.Conn) { disconnected <- conn }
+	client.OnReconnect = func(conn *birect.Conn) { reconnected <- conn }
+
+	type Echo struct{ Text string }
+	server.HandleJSONReq("TestClientReconnect", func(req *birect.JSONReq) (res interface{}, err error) {
+		var par Echo
+		req.ParseParams(&par)
+		return par, nil
+	})
+	client.HandleJSONReq("TestClientReconnectFromServer", func(req *birect.JSONReq) (res interface{}, err error) {
+		return Echo{"From client"}, nil
+	})
+
+	// Close the connection from the server side, once the server has registered it
+	var res Echo
+	assert(t, client.SendJSONReq("TestClientReconnect", &res, Echo{"Before"}) == nil)
+	assert(t, res.Text == "Before")
+	oldConn := client.CurrentConn()
+	closedServerConns := server.Conns()
+	for _, conn := range closedServerConns {
+		conn.Close()
+	}
+
+	assert(t, <-disconnected == oldConn)
+	newConn := <-reconnected
+	assert(t, client.CurrentConn() == newConn && newConn != oldConn)
+	assert(t, client.Conn() == newConn && client.Info() != nil && client.Request() == nil)
+	assert(t, oldConn.SendJSONReq("TestClientReconnect", &res, Echo{"Old"}) != nil)
+	assert(t, client.SendJSONReq("TestClientReconnect", &res, Echo{"After"}) == nil)
+	assert(t, res.Text == "After")
+
+	// Handlers registered on the client are kept across reconnects
+	var serverConn *birect.Conn
+	for _, conn := range server.Conns() {
+		if conn != closedServerConns[0] {
+			serverConn = conn
+		}
+	}
+	assert(t, serverConn.SendJSONReq("TestClientReconnectFromServer", &res, nil) == nil)
+	assert(t, res.Text == "From client")
+
+	// Closing the client stops it from reconnecting
+	assert(t, client.Close() == nil)
+	assert(t, <-disconnected == newConn)
+	select {
+	case <-reconnected:
+		t.Fatal("Client reconnected after Close")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := birect.Backoff{MinDelay: 100 * time.Millisecond, MaxDelay: time.Second, Factor: 2}
+	assert(t, backoff.Delay(0) == 100*time.Millisecond)
+	assert(t, backoff.Delay(2) == 400*time.Millisecond)
+	assert(t, backoff.Delay(10) == time.Second)
+
+	backoff.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := backoff.Delay(1)
+		assert(t, delay >= 100*time.Millisecond && delay <= 300*time.Millisecond, delay)
+	}
+}
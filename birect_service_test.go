@@ -0,0 +1,58 @@
+package birect_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
+)
+
+// CalcParams are the params of Calc.Add
+type CalcParams struct{ A, B int }
+
+// CalcResult is the result of Calc.Add
+type CalcResult struct{ Sum int }
+
+// Calc is a service for TestRegisterService
+type Calc struct{}
+
+// Add is a JSON method
+func (c *Calc) Add(ctx context.Context, par CalcParams) (CalcResult, error) {
+	return CalcResult{par.A + par.B}, nil
+}
+
+// Echo is a proto method
+func (c *Calc) Echo(ctx context.Context, conn *birect.Conn, msg *wire.Message) (*wire.Message, error) {
+	return &wire.Message{Name: "Re: " + msg.Name}, nil
+}
+
+// Fail is a JSON method that fails
+func (c *Calc) Fail(ctx context.Context, par *CalcParams) (*CalcResult, error) {
+	return nil, &birect.ResponseError{Code: "FAILED"}
+}
+
+// Unsupported does not get registered
+func (c *Calc) Unsupported(par CalcParams) CalcResult {
+	return CalcResult{}
+}
+
+func TestRegisterService(t *testing.T) {
+	server, client := setupServerClient()
+	assert(t, server.RegisterService(&Calc{}) == nil)
+	assert(t, server.RegisterService(&struct{}{}) != nil)
+
+	var res CalcResult
+	assert(t, client.SendJSONReq("Calc.Add", &res, CalcParams{1, 2}) == nil)
+	assert(t, res.Sum == 3)
+
+	var msg wire.Message
+	assert(t, client.SendProtoReq("Calc.Echo", &msg, &wire.Message{Name: "Hi"}) == nil)
+	assert(t, msg.Name == "Re: Hi")
+
+	err := client.SendJSONReq("Calc.Fail", &res, CalcParams{})
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == "FAILED", err)
+
+	assert(t, client.SendJSONReq("Calc.Unsupported", &res, CalcParams{}) != nil)
+}
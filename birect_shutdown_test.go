@@ -0,0 +1,160 @@
+package birect_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestShutdown(t *testing.T) {
+	lastPort += 1
+	server := birect.NewServer()
+	errChan := server.ListenAndServe(fmt.Sprintf("localhost:%d", lastPort))
+	address := fmt.Sprintf("http://localhost:%d", lastPort)
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	server.HandleJSONReq("TestShutdown", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-release
+		return "Done", nil
+	})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	results := make(chan error, 1)
+	var res string
+	go func() { results <- client.SendJSONReq("TestShutdown", &res, nil) }()
+	<-started
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.Shutdown(context.Background()) }()
+
+	// New connections get refused, while the in-flight request is being handled
+	refused := false
+	for i := 0; i < 100 && !refused; i++ {
+		if client, err := birect.Connect(address); err == nil {
+			client.Close()
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			refused = true
+		}
+	}
+	assert(t, refused)
+	assert(t, len(shutdownErr) == 0)
+
+	close(release)
+	assert(t, <-results == nil)
+	assert(t, res == "Done", res)
+	assert(t, <-shutdownErr == nil)
+	assert(t, <-errChan == http.ErrServerClosed)
+	assert(t, waitForConnCount(server.Handler, 0))
+}
+
+func TestShutdownRejectsRequests(t *testing.T) {
+	server, address := setupServer()
+	started := make(chan bool, 1)
+	release := make(chan bool)
+	server.HandleJSONReq("TestShutdownRejectsRequests", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-release
+		return nil, nil
+	})
+	server.HandleStream("TestShutdownRejectsRequests", func(stream *birect.Stream) error {
+		return nil
+	})
+	client, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer client.Close()
+
+	conn := client.CurrentConn()
+	results := make(chan error, 1)
+	go func() { results <- conn.SendJSONReq("TestShutdownRejectsRequests", nil, nil) }()
+	<-started
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.Shutdown(context.Background()) }()
+	refused := false
+	for i := 0; i < 100 && !refused; i++ {
+		if client, err := birect.Connect(address); err == nil {
+			client.Close()
+			time.Sleep(10 * time.Millisecond)
+		} else {
+			refused = true
+		}
+	}
+	assert(t, refused)
+
+	// Requests and streams that arrive once shutdown has started get rejected, and are not waited for
+	err = conn.SendJSONReq("TestShutdownRejectsRequests", nil, nil)
+	resErr, ok := err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.ShuttingDownCode && resErr.Retryable && resErr.RetryAfter > 0, err)
+	stream, err := conn.OpenStream("TestShutdownRejectsRequests")
+	assert(t, err == nil, err)
+	var value string
+	err = stream.Recv(&value)
+	resErr, ok = err.(*birect.ResponseError)
+	assert(t, ok && resErr.Code == birect.ShuttingDownCode, err)
+	assert(t, len(started) == 0 && len(shutdownErr) == 0)
+
+	close(release)
+	assert(t, <-results == nil)
+	assert(t, <-shutdownErr == nil)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	server, client := setupServerClient()
+	disconnected := make(chan bool, 1)
+	server.DisconnectHandler = func(*birect.Conn) { disconnected <- true }
+	started := make(chan bool, 1)
+	block := make(chan bool)
+	defer close(block)
+	server.HandleJSONReq("TestShutdownTimeout", func(req *birect.JSONReq) (res interface{}, err error) {
+		started <- true
+		<-block
+		return nil, nil
+	})
+
+	results := make(chan error, 1)
+	go func() { results <- client.SendJSONReq("TestShutdownTimeout", nil, nil) }()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert(t, server.Shutdown(ctx) == context.DeadlineExceeded)
+	assert(t, <-results == birect.ErrConnClosed)
+	<-disconnected
+	client.Close()
+}
+
+func waitForConnCount(server *birect.Handler, count int) bool {
+	for i := 0; i < 100; i++ {
+		if server.ConnCount() == count {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestShutdownStreams(t *testing.T) {
+	server, client := setupServerClient()
+	started := make(chan bool, 1)
+	block := make(chan bool)
+	defer close(block)
+	server.HandleStream("TestShutdownStreams", func(stream *birect.Stream) error {
+		started <- true
+		<-block
+		return nil
+	})
+
+	// Accepted streams are in flight until their handler returns
+	_, err := client.OpenStream("TestShutdownStreams")
+	assert(t, err == nil, err)
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert(t, server.Shutdown(ctx) == context.DeadlineExceeded)
+	client.Close()
+}
@@ -0,0 +1,273 @@
+package birect_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+	"github.com/marcuswestin/go-birect/internal/wire"
+	"github.com/marcuswestin/go-errs"
+)
+
+func TestStreamJSONReq(t *testing.T) {
+	server, client := setupServerClient()
+
+	type CountParams struct{ To int }
+	type CountChunk struct{ Num int }
+	server.HandleStreamJSONReq("Count", func(req *birect.StreamJSONReq) error {
+		var params CountParams
+		req.ParseParams(&params)
+		for i := 1; i <= params.To; i++ {
+			if err := req.Send(CountChunk{i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	reader, err := client.SendStreamJSONReq(context.Background(), "Count", CountParams{500})
+	assert(t, err == nil, err)
+	var nums []int
+	for {
+		var chunk CountChunk
+		err = reader.Recv(&chunk)
+		if err == io.EOF {
+			break
+		}
+		assert(t, err == nil, err)
+		nums = append(nums, chunk.Num)
+	}
+	assert(t, len(nums) == 500)
+	assert(t, nums[0] == 1 && nums[499] == 500)
+}
+
+func TestStreamError(t *testing.T) {
+	server, client := setupServerClient()
+
+	server.HandleStreamProtoReq("Fail", func(req *birect.StreamProtoReq) error {
+		req.Send(&wire.Message{Name: "first"})
+		return errs.New(nil, "Stream failed")
+	})
+
+	reader, err := client.SendStreamProtoReq(context.Background(), "Fail", &wire.Message{})
+	assert(t, err == nil, err)
+	var msg wire.Message
+	err = reader.Recv(&msg)
+	assert(t, err == nil && msg.Name == "first", err)
+	err = reader.Recv(&msg)
+	assert(t, err != nil && err != io.EOF && err.Error() == "Stream failed", err)
+
+	reader, err = client.SendStreamProtoReq(context.Background(), "Missing", &wire.Message{})
+	assert(t, err == nil, err)
+	err = reader.Recv(&msg)
+	assert(t, err != nil && err != io.EOF, err)
+}
+
+func TestStreamClose(t *testing.T) {
+	server, client := setupServerClient()
+
+	handlerErr := make(chan error, 1)
+	server.HandleStreamJSONReq("Forever", func(req *birect.StreamJSONReq) error {
+		for {
+			if err := req.Send("tick"); err != nil {
+				handlerErr <- err
+				return err
+			}
+		}
+	})
+
+	reader, err := client.SendStreamJSONReq(context.Background(), "Forever", nil)
+	assert(t, err == nil, err)
+	var tick string
+	assert(t, reader.Recv(&tick) == nil && tick == "tick")
+	reader.Close()
+	assert(t, reader.Recv(&tick) == io.EOF)
+	assert(t, <-handlerErr == context.Canceled)
+}
+
+func TestStreamFlowControl(t *testing.T) {
+	server, client := setupServerClient()
+
+	sent := make(chan int, 200)
+	server.HandleStreamJSONReq("TestStreamFlowControl", func(req *birect.StreamJSONReq) error {
+		for i := 1; i <= 200; i++ {
+			if err := req.Send(i); err != nil {
+				return err
+			}
+			sent <- i
+		}
+		return nil
+	})
+	server.HandleJSONReq("TestStreamFlowControlPing", func(req *birect.JSONReq) (interface{}, error) {
+		return "Pong", nil
+	})
+
+	// The handler fills the reader's buffer, and then waits for it to make room
+	reader, err := client.SendStreamJSONReq(context.Background(), "TestStreamFlowControl", nil)
+	assert(t, err == nil, err)
+	for i := 1; i <= 64; i++ {
+		assert(t, <-sent == i)
+	}
+	select {
+	case i := <-sent:
+		t.Fatal("Expected handler to wait for the reader", i)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The stalled reader does not hold up the rest of the conn
+	var pong string
+	assert(t, client.SendJSONReq("TestStreamFlowControlPing", &pong, nil) == nil)
+	assert(t, pong == "Pong", pong)
+
+	for i := 1; i <= 200; i++ {
+		var num int
+		assert(t, reader.Recv(&num) == nil && num == i, num)
+	}
+	var num int
+	assert(t, reader.Recv(&num) == io.EOF)
+}
+
+func TestBidirectionalStream(t *testing.T) {
+	server, client := setupServerClient()
+
+	type Edit struct{ Text string }
+	server.HandleStream("Echo", func(stream *birect.Stream) error {
+		for {
+			var edit Edit
+			err := stream.Recv(&edit)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = stream.SendJSON(Edit{"Echo: " + edit.Text}); err != nil {
+				return err
+			}
+		}
+	})
+
+	stream, err := client.OpenStream("Echo")
+	assert(t, err == nil, err)
+	go func() {
+		for _, text := range []string{"a", "b", "c"} {
+			stream.SendJSON(Edit{text})
+		}
+		stream.CloseSend()
+	}()
+	var echoes []string
+	for {
+		var edit Edit
+		err = stream.Recv(&edit)
+		if err == io.EOF {
+			break
+		}
+		assert(t, err == nil, err)
+		echoes = append(echoes, edit.Text)
+	}
+	assert(t, len(echoes) == 3 && echoes[2] == "Echo: c", echoes)
+	assert(t, stream.SendJSON(Edit{"d"}) != nil)
+}
+
+func TestBidirectionalStreamClose(t *testing.T) {
+	server, client := setupServerClient()
+
+	handlerErr := make(chan error, 1)
+	server.HandleStream("TestBidirectionalStreamClose", func(stream *birect.Stream) error {
+		var value string
+		err := stream.Recv(&value)
+		<-stream.Context().Done()
+		handlerErr <- err
+		return err
+	})
+
+	// Closing the stream stops both sides
+	stream, err := client.OpenStream("TestBidirectionalStreamClose")
+	assert(t, err == nil, err)
+	assert(t, stream.Close() == nil)
+	assert(t, <-handlerErr == context.Canceled)
+	var value string
+	assert(t, stream.Recv(&value) == io.EOF)
+	assert(t, stream.SendJSON("Hi") != nil)
+	assert(t, stream.Context().Err() == context.Canceled)
+
+	// So does cancelling the context that the stream was opened with
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err = client.OpenStreamContext(ctx, "TestBidirectionalStreamClose")
+	assert(t, err == nil, err)
+	cancel()
+	assert(t, <-handlerErr == context.Canceled)
+	assert(t, stream.Recv(&value) == context.Canceled)
+}
+
+func TestBidirectionalStreamFlowControl(t *testing.T) {
+	server, client := setupServerClient()
+
+	sent := make(chan int, 200)
+	server.HandleStream("TestBidirectionalStreamFlowControl", func(stream *birect.Stream) error {
+		for i := 1; i <= 200; i++ {
+			if err := stream.SendJSON(i); err != nil {
+				return err
+			}
+			sent <- i
+		}
+		return nil
+	})
+	server.HandleJSONReq("TestBidirectionalStreamFlowControlPing", func(req *birect.JSONReq) (interface{}, error) {
+		return "Pong", nil
+	})
+
+	// The handler fills the opener's buffer, and then waits for it to make room
+	stream, err := client.OpenStream("TestBidirectionalStreamFlowControl")
+	assert(t, err == nil, err)
+	for i := 1; i <= 64; i++ {
+		assert(t, <-sent == i)
+	}
+	select {
+	case i := <-sent:
+		t.Fatal("Expected handler to wait for the opener", i)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The stalled stream does not hold up the rest of the conn
+	var pong string
+	assert(t, client.SendJSONReq("TestBidirectionalStreamFlowControlPing", &pong, nil) == nil)
+	assert(t, pong == "Pong", pong)
+
+	for i := 1; i <= 200; i++ {
+		var num int
+		assert(t, stream.Recv(&num) == nil && num == i, num)
+	}
+	var num int
+	assert(t, stream.Recv(&num) == io.EOF)
+}
+
+func TestStreamFromServer(t *testing.T) {
+	server, client := setupServerClient()
+
+	client.HandleStream("Names", func(stream *birect.Stream) error {
+		stream.SendProto(&wire.Message{Name: "client"})
+		return errs.New(nil, "Client stream error")
+	})
+	connected := make(chan *birect.Conn, 1)
+	server.HandleJSONReq("Hello", func(req *birect.JSONReq) (interface{}, error) {
+		connected <- req.Conn
+		return nil, nil
+	})
+	assert(t, client.SendJSONReq("Hello", nil, nil) == nil)
+
+	conn := <-connected
+	stream, err := conn.OpenStream("Names")
+	assert(t, err == nil, err)
+	var msg wire.Message
+	assert(t, stream.Recv(&msg) == nil && msg.Name == "client")
+	err = stream.Recv(&msg)
+	assert(t, err != nil && err.Error() == "Client stream error", err)
+
+	stream, err = conn.OpenStream("Missing")
+	assert(t, err == nil, err)
+	err = stream.Recv(&msg)
+	assert(t, err != nil && err != io.EOF, err)
+}
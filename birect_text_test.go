@@ -0,0 +1,29 @@
+package birect_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestTextReq(t *testing.T) {
+	server, client := setupServerClient()
+
+	server.HandleTextReq("Upper", func(req *birect.TextReq) (string, error) {
+		return strings.ToUpper(req.Text()), nil
+	})
+
+	var res string
+	err := client.SendTextReq("Upper", &res, "hello")
+	assert(t, err == nil, err)
+	assert(t, res == "HELLO", res)
+
+	// Empty responses are empty strings, rather than leaving res as is
+	err = client.SendTextReq("Upper", &res, "")
+	assert(t, err == nil, err)
+	assert(t, res == "", res)
+
+	err = client.SendTextReq("Missing", &res, "hello")
+	assert(t, err != nil)
+}
@@ -0,0 +1,128 @@
+package birect_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestTopics(t *testing.T) {
+	server, client := setupServerClient()
+
+	// Requests are handled after any subscriptions sent before them
+	server.HandleJSONReq("TestTopicsSync", func(req *birect.JSONReq) (interface{}, error) {
+		return nil, nil
+	})
+	sync := func() { assert(t, client.SendJSONReq("TestTopicsSync", nil, nil) == nil) }
+
+	type Price struct{ Cents int }
+	server.RetainTopics("prices.*")
+	assert(t, server.Publish("prices.btc", Price{100}) == nil)
+
+	// Retained values get delivered as part of subscribing
+	prices := make(chan Price, 10)
+	err := client.Subscribe("prices.*", func(pub *birect.Publication) {
+		var price Price
+		assert(t, pub.ParseValue(&price) == nil)
+		assert(t, pub.Topic == "prices.btc")
+		prices <- price
+	})
+	assert(t, err == nil, err)
+	sync()
+	assert(t, len(prices) == 1 && (<-prices).Cents == 100)
+
+	topics := make(chan string, 10)
+	assert(t, client.Subscribe("orders.>", func(pub *birect.Publication) { topics <- pub.Topic }) == nil)
+	sync()
+	assert(t, server.Publish("orders", nil) == nil)
+	assert(t, server.Publish("orders.eu.created", nil) == nil)
+	assert(t, server.Publish("prices.btc", Price{200}) == nil)
+	assert(t, (<-prices).Cents == 200)
+	assert(t, len(topics) == 1 && <-topics == "orders.eu.created")
+
+	// Publications are delivered in order, so once the price arrives the order would have too
+	assert(t, client.Unsubscribe("orders.>") == nil)
+	sync()
+	assert(t, server.Publish("orders.us.created", nil) == nil)
+	assert(t, server.Publish("prices.btc.usd", Price{300}) == nil)
+	assert(t, server.Publish("prices.btc", Price{300}) == nil)
+	assert(t, (<-prices).Cents == 300)
+	assert(t, len(topics) == 0 && len(prices) == 0)
+}
+
+func TestSubscribeFromHandler(t *testing.T) {
+	server, client := setupServerClient()
+
+	// Handlers run on the read loop, so Subscribe must not wait for the server
+	published := make(chan string, 1)
+	client.HandleJSONMessage("TestSubscribeFromHandler", func(msg *birect.JSONMessage) {
+		err := client.Subscribe("news", func(pub *birect.Publication) {
+			var headline string
+			pub.ParseValue(&headline)
+			published <- headline
+		})
+		assert(t, err == nil, err)
+		assert(t, msg.Conn.SendJSONMessage("TestSubscribeFromHandlerDone", nil) == nil)
+	})
+	server.HandleJSONMessage("TestSubscribeFromHandlerDone", func(msg *birect.JSONMessage) {
+		assert(t, server.Publish("news", "Subscribed") == nil)
+	})
+	server.HandleJSONReq("TestSubscribeFromHandler", func(req *birect.JSONReq) (interface{}, error) {
+		return nil, req.Conn.SendJSONMessage("TestSubscribeFromHandler", nil)
+	})
+
+	assert(t, client.SendJSONReq("TestSubscribeFromHandler", nil, nil) == nil)
+	assert(t, <-published == "Subscribed")
+}
+
+func TestPublishToStalledSubscriber(t *testing.T) {
+	server, address := setupServer()
+	stalled, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer stalled.Close()
+	unblock := make(chan bool)
+	defer close(unblock)
+	server.HandleJSONMessage("TestPublishToStalledSubscriberBlock", func(msg *birect.JSONMessage) {
+		assert(t, msg.Conn.SendJSONMessage("TestPublishToStalledSubscriberBlock", nil) == nil)
+	})
+	stalled.HandleJSONMessage("TestPublishToStalledSubscriberBlock", func(msg *birect.JSONMessage) {
+		<-unblock
+	})
+	server.HandleJSONReq("TestPublishToStalledSubscriberSync", func(req *birect.JSONReq) (interface{}, error) {
+		return nil, nil
+	})
+	assert(t, stalled.Subscribe("big", func(pub *birect.Publication) {}) == nil)
+	assert(t, stalled.SendJSONReq("TestPublishToStalledSubscriberSync", nil, nil) == nil)
+
+	// The stalled client stops reading, so its connection fills up with publications
+	assert(t, stalled.SendJSONMessage("TestPublishToStalledSubscriberBlock", nil) == nil)
+	published := make(chan bool)
+	go func() {
+		big := strings.Repeat("x", 1024*1024)
+		for i := 0; i < 32; i++ {
+			assert(t, server.Publish("big", big) == nil)
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Publish not to wait for the stalled subscriber")
+	}
+
+	// Other subscribers still get their publications, and can still subscribe
+	other, err := birect.Connect(address)
+	assert(t, err == nil, err)
+	defer other.Close()
+	news := make(chan string, 1)
+	assert(t, other.Subscribe("news", func(pub *birect.Publication) {
+		var headline string
+		pub.ParseValue(&headline)
+		news <- headline
+	}) == nil)
+	assert(t, other.SendJSONReq("TestPublishToStalledSubscriberSync", nil, nil) == nil)
+	assert(t, server.Publish("news", "Still there") == nil)
+	assert(t, <-news == "Still there")
+}
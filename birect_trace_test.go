@@ -0,0 +1,169 @@
+package birect_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/marcuswestin/go-birect"
+)
+
+func TestTrace(t *testing.T) {
+	server, client := setupServerClient()
+	serverSpans := birect.NewSpanRecorder()
+	clientSpans := birect.NewSpanRecorder()
+	server.SetTracer(serverSpans)
+	client.SetTracer(clientSpans)
+
+	// The server handler calls back to the client, which continues the trace
+	server.HandleJSONReq("TestTrace", func(req *birect.JSONReq) (res interface{}, err error) {
+		var clientTraceID string
+		err = req.Conn.SendJSONReqContext(req.Context(), "TestTraceBack", &clientTraceID, nil)
+		return clientTraceID, err
+	})
+	client.HandleJSONReq("TestTraceBack", func(req *birect.JSONReq) (res interface{}, err error) {
+		tc, ok := birect.TraceFromContext(req.Context())
+		assert(t, ok)
+		return tc.TraceID.String(), nil
+	})
+
+	parent, err := birect.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert(t, err == nil, err)
+	var traceID string
+	err = client.SendJSONReqContext(birect.ContextWithTrace(context.Background(), parent), "TestTrace", &traceID, nil)
+	assert(t, err == nil, err)
+	assert(t, traceID == "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+
+	// client -> server -> client, with every span the child of the one before it
+	reqSpan := findSpan(t, clientSpans, "TestTrace", birect.SpanKindClient)
+	handleSpan := findSpan(t, serverSpans, "TestTrace", birect.SpanKindServer)
+	callbackSpan := findSpan(t, serverSpans, "TestTraceBack", birect.SpanKindClient)
+	handleCallbackSpan := findSpan(t, clientSpans, "TestTraceBack", birect.SpanKindServer)
+	chain := []birect.Span{reqSpan, handleSpan, callbackSpan, handleCallbackSpan}
+	assert(t, reqSpan.Parent == parent.SpanID)
+	for i, span := range chain {
+		assert(t, span.Trace.TraceID == parent.TraceID, span)
+		assert(t, span.Err == nil && !span.End.Before(span.Start), span)
+		if i > 0 {
+			assert(t, span.Parent == chain[i-1].Trace.SpanID, i, span)
+		}
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	server, client := setupServerClient()
+	parent, err := birect.ParseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	assert(t, err == nil, err)
+	ctx := birect.ContextWithTrace(context.Background(), parent)
+
+	// Without a tracer, trace contexts are passed along as is
+	server.HandleJSONReq("TestTracePropagation", func(req *birect.JSONReq) (res interface{}, err error) {
+		tc, _ := birect.TraceFromContext(req.Context())
+		return tc.String(), nil
+	})
+	var traceparent string
+	assert(t, client.SendJSONReqContext(ctx, "TestTracePropagation", &traceparent, nil) == nil)
+	assert(t, traceparent == parent.String(), traceparent)
+
+	received := make(chan birect.TraceContext, 1)
+	server.HandleJSONMessage("TestTracePropagation", func(msg *birect.JSONMessage) {
+		tc, _ := birect.TraceFromContext(msg.Context())
+		received <- tc
+	})
+	assert(t, client.SendJSONMessageContext(ctx, "TestTracePropagation", nil) == nil)
+	assert(t, <-received == parent)
+
+	// Requests without trace context get none
+	assert(t, client.SendJSONReq("TestTracePropagation", &traceparent, nil) == nil)
+	assert(t, traceparent == birect.TraceContext{}.String(), traceparent)
+
+	for _, bad := range []string{"", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331", "00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01", "ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"} {
+		_, err := birect.ParseTraceparent(bad)
+		assert(t, err != nil, bad)
+	}
+}
+
+func TestTraceStreams(t *testing.T) {
+	server, client := setupServerClient()
+	serverSpans := birect.NewSpanRecorder()
+	clientSpans := birect.NewSpanRecorder()
+	server.SetTracer(serverSpans)
+	client.SetTracer(clientSpans)
+	parent, err := birect.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert(t, err == nil, err)
+	ctx := birect.ContextWithTrace(context.Background(), parent)
+
+	server.HandleStreamJSONReq("TestTraceStreamsReq", func(req *birect.StreamJSONReq) error {
+		tc, _ := birect.TraceFromContext(req.Context())
+		return req.Send(tc.TraceID.String())
+	})
+	server.HandleStream("TestTraceStreams", func(stream *birect.Stream) error {
+		tc, _ := birect.TraceFromContext(stream.Context())
+		return stream.SendJSON(tc.TraceID.String())
+	})
+	server.HandleStream("TestTraceStreamsError", func(stream *birect.Stream) error {
+		return &birect.ResponseError{Code: "NOPE"}
+	})
+
+	// Streaming requests
+	reader, err := client.SendStreamJSONReq(ctx, "TestTraceStreamsReq", nil)
+	assert(t, err == nil, err)
+	var traceID string
+	assert(t, reader.Recv(&traceID) == nil)
+	assert(t, traceID == parent.TraceID.String(), traceID)
+	assert(t, reader.Recv(&traceID) == io.EOF)
+	assertSpanChain(t, parent, findSpan(t, clientSpans, "TestTraceStreamsReq", birect.SpanKindClient),
+		findSpan(t, serverSpans, "TestTraceStreamsReq", birect.SpanKindServer))
+
+	// Bidirectional streams
+	stream, err := client.OpenStreamContext(ctx, "TestTraceStreams")
+	assert(t, err == nil, err)
+	assert(t, stream.Recv(&traceID) == nil)
+	assert(t, traceID == parent.TraceID.String(), traceID)
+	assert(t, stream.Recv(&traceID) == io.EOF)
+	assertSpanChain(t, parent, findSpan(t, clientSpans, "TestTraceStreams", birect.SpanKindClient),
+		findSpan(t, serverSpans, "TestTraceStreams", birect.SpanKindServer))
+
+	// Both sides' spans get the handler error
+	stream, err = client.OpenStreamContext(ctx, "TestTraceStreamsError")
+	assert(t, err == nil, err)
+	assert(t, stream.Recv(&traceID) != nil)
+	for _, span := range []birect.Span{
+		findSpan(t, clientSpans, "TestTraceStreamsError", birect.SpanKindClient),
+		findSpan(t, serverSpans, "TestTraceStreamsError", birect.SpanKindServer),
+	} {
+		var resErr *birect.ResponseError
+		assert(t, errors.As(span.Err, &resErr) && resErr.Code == "NOPE", span)
+	}
+}
+
+// assertSpanChain asserts that the given spans succeeded, and that each is the child of the one before it.
+func assertSpanChain(t *testing.T, parent birect.TraceContext, chain ...birect.Span) {
+	for i, span := range chain {
+		assert(t, span.Trace.TraceID == parent.TraceID, span)
+		assert(t, span.Err == nil && !span.End.Before(span.Start), span)
+		if i == 0 {
+			assert(t, span.Parent == parent.SpanID, span)
+		} else {
+			assert(t, span.Parent == chain[i-1].Trace.SpanID, i, span)
+		}
+	}
+}
+
+// findSpan waits for the span with the given name and kind, since
+// handlers export their spans after sending the response.
+func findSpan(t *testing.T, recorder *birect.SpanRecorder, name string, kind birect.SpanKind) birect.Span {
+	for i := 0; i < 100; i++ {
+		for _, span := range recorder.Spans() {
+			if span.Name == name && span.Kind == kind {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Missing span", name, kind)
+	return birect.Span{}
+}
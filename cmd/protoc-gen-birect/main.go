@@ -0,0 +1,194 @@
+// Command protoc-gen-birect is a protoc plugin that generates typed birect
+// server interfaces and client stubs for the services in .proto files.
+//
+// Install it on your PATH and run protoc with --birect_out, e.g
+//
+//	protoc --go_out=. --birect_out=. service.proto
+//
+// For every service Foo with an rpc Bar(Req) returns (Res), protoc-gen-birect generates
+//
+//	type FooServer interface {
+//		Bar(ctx context.Context, conn *birect.Conn, params *Req) (*Res, error)
+//	}
+//	func RegisterFooServer(registry birect.ProtoReqHandlerRegistry, srv FooServer)
+//	func NewFooClient(sender birect.ProtoReqSender) *FooClient
+//	func (c *FooClient) Bar(ctx context.Context, params *Req) (*Res, error)
+//
+// Requests are named "Foo.Bar", the same as Handler.RegisterService would name them.
+// Only unary rpcs with request and response types from the same package are supported.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+func main() {
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fail(err)
+	}
+	var req plugin.CodeGeneratorRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		fail(err)
+	}
+	data, err = proto.Marshal(generate(&req))
+	if err != nil {
+		fail(err)
+	}
+	if _, err := os.Stdout.Write(data); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "protoc-gen-birect:", err)
+	os.Exit(1)
+}
+
+// generate generates a .birect.go file for every file to generate that has services.
+func generate(req *plugin.CodeGeneratorRequest) *plugin.CodeGeneratorResponse {
+	res := &plugin.CodeGeneratorResponse{}
+	filesByName := make(map[string]*descriptor.FileDescriptorProto)
+	for _, file := range req.GetProtoFile() {
+		filesByName[file.GetName()] = file
+	}
+	for _, name := range req.GetFileToGenerate() {
+		file := filesByName[name]
+		if file == nil || len(file.GetService()) == 0 {
+			continue
+		}
+		content, err := generateFile(file)
+		if err != nil {
+			res.Error = proto.String(name + ": " + err.Error())
+			return res
+		}
+		res.File = append(res.File, &plugin.CodeGeneratorResponse_File{
+			Name:    proto.String(strings.TrimSuffix(name, ".proto") + ".birect.go"),
+			Content: proto.String(content),
+		})
+	}
+	return res
+}
+
+func generateFile(file *descriptor.FileDescriptorProto) (string, error) {
+	var b bytes.Buffer
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+	p("// Code generated by protoc-gen-birect. DO NOT EDIT.")
+	p("// source: %s", file.GetName())
+	p("")
+	p("package %s", goPackageName(file))
+	p("")
+	p("import (")
+	p("\t\"context\"")
+	p("")
+	p("\t\"github.com/marcuswestin/go-birect\"")
+	p(")")
+	for _, service := range file.GetService() {
+		svc := service.GetName()
+		var methods []*descriptor.MethodDescriptorProto
+		for _, method := range service.GetMethod() {
+			if method.GetClientStreaming() || method.GetServerStreaming() {
+				return "", fmt.Errorf("%s.%s: streaming rpcs are not supported", svc, method.GetName())
+			}
+			methods = append(methods, method)
+		}
+		types := make(map[*descriptor.MethodDescriptorProto][2]string)
+		for _, method := range methods {
+			inType, err := goTypeName(file, method.GetInputType())
+			if err != nil {
+				return "", err
+			}
+			outType, err := goTypeName(file, method.GetOutputType())
+			if err != nil {
+				return "", err
+			}
+			types[method] = [2]string{inType, outType}
+		}
+
+		p("")
+		p("// %sServer is the server API for the %s service.", svc, svc)
+		p("type %sServer interface {", svc)
+		for _, method := range methods {
+			p("\t%s(ctx context.Context, conn *birect.Conn, params *%s) (*%s, error)", method.GetName(), types[method][0], types[method][1])
+		}
+		p("}")
+		p("")
+		p("// Register%sServer registers srv's methods as proto request handlers.", svc)
+		p("func Register%sServer(registry birect.ProtoReqHandlerRegistry, srv %sServer) {", svc, svc)
+		for _, method := range methods {
+			p("\tregistry.HandleProtoReq(%q, func(req *birect.ProtoReq) (birect.Proto, error) {", svc+"."+method.GetName())
+			p("\t\tparams := new(%s)", types[method][0])
+			p("\t\treq.ParseParams(params)")
+			p("\t\treturn srv.%s(req.Context(), req.Conn, params)", method.GetName())
+			p("\t})")
+		}
+		p("}")
+		p("")
+		p("// %sClient sends requests to a %sServer.", svc, svc)
+		p("type %sClient struct {", svc)
+		p("\tsender birect.ProtoReqSender")
+		p("}")
+		p("")
+		p("// New%sClient returns a %sClient that sends requests with sender, e.g a *birect.Client.", svc, svc)
+		p("func New%sClient(sender birect.ProtoReqSender) *%sClient {", svc, svc)
+		p("\treturn &%sClient{sender}", svc)
+		p("}")
+		for _, method := range methods {
+			p("")
+			p("// %s sends a %s.%s request.", method.GetName(), svc, method.GetName())
+			p("func (c *%sClient) %s(ctx context.Context, params *%s) (*%s, error) {", svc, method.GetName(), types[method][0], types[method][1])
+			p("\tres := new(%s)", types[method][1])
+			p("\tif err := c.sender.SendProtoReqContext(ctx, %q, res, params); err != nil {", svc+"."+method.GetName())
+			p("\t\treturn nil, err")
+			p("\t}")
+			p("\treturn res, nil")
+			p("}")
+		}
+	}
+	formatted, err := format.Source(b.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+// goPackageName returns the Go package name for file, like protoc-gen-go does.
+func goPackageName(file *descriptor.FileDescriptorProto) string {
+	name := file.GetPackage()
+	if goPackage := file.GetOptions().GetGoPackage(); goPackage != "" {
+		name = goPackage
+		if i := strings.Index(name, ";"); i >= 0 {
+			name = name[i+1:]
+		}
+		name = path.Base(name)
+	}
+	if name == "" {
+		name = strings.TrimSuffix(path.Base(file.GetName()), ".proto")
+	}
+	return strings.NewReplacer(".", "_", "-", "_").Replace(name)
+}
+
+// goTypeName returns the Go name for a fully qualified proto message name, e.g
+// ".pkg.Outer.Inner" becomes "Outer_Inner".
+func goTypeName(file *descriptor.FileDescriptorProto, protoName string) (string, error) {
+	prefix := "."
+	if file.GetPackage() != "" {
+		prefix = "." + file.GetPackage() + "."
+	}
+	if !strings.HasPrefix(protoName, prefix) {
+		return "", fmt.Errorf("%s: types from other packages are not supported", protoName)
+	}
+	return strings.Replace(strings.TrimPrefix(protoName, prefix), ".", "_", -1), nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+)
+
+func TestGenerate(t *testing.T) {
+	file := &descriptor.FileDescriptorProto{
+		Name:    proto.String("protos/echo.proto"),
+		Package: proto.String("echo"),
+		Options: &descriptor.FileOptions{GoPackage: proto.String("github.com/example/echopb")},
+		Service: []*descriptor.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptor.MethodDescriptorProto{{
+				Name:       proto.String("Say"),
+				InputType:  proto.String(".echo.SayRequest"),
+				OutputType: proto.String(".echo.SayRequest.Reply"),
+			}},
+		}},
+	}
+	res := generate(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/echo.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{file},
+	})
+	if res.Error != nil {
+		t.Fatal(res.GetError())
+	}
+	if len(res.File) != 1 || res.File[0].GetName() != "protos/echo.birect.go" {
+		t.Fatal("Unexpected files", res.File)
+	}
+	content := res.File[0].GetContent()
+	typeCheck(t, content)
+	for _, expected := range []string{
+		"package echopb",
+		"Say(ctx context.Context, conn *birect.Conn, params *SayRequest) (*SayRequest_Reply, error)",
+		`registry.HandleProtoReq("Echo.Say"`,
+		`c.sender.SendProtoReqContext(ctx, "Echo.Say", res, params)`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatal("Missing", expected, "in", content)
+		}
+	}
+
+	file.Service[0].Method[0].ServerStreaming = proto.Bool(true)
+	res = generate(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/echo.proto"},
+		ProtoFile:      []*descriptor.FileDescriptorProto{file},
+	})
+	if res.Error == nil {
+		t.Fatal("Expected an error for streaming rpcs")
+	}
+}
+
+// echoStubs stands in for the protoc-gen-go output for echo.proto, and
+// checks that the generated code can be used with a Handler and a Client.
+const echoStubs = `package echopb
+
+import "github.com/marcuswestin/go-birect"
+
+type SayRequest struct{}
+
+func (*SayRequest) Reset()         {}
+func (*SayRequest) String() string { return "" }
+func (*SayRequest) ProtoMessage()  {}
+
+type SayRequest_Reply struct{}
+
+func (*SayRequest_Reply) Reset()         {}
+func (*SayRequest_Reply) String() string { return "" }
+func (*SayRequest_Reply) ProtoMessage()  {}
+
+func use(server *birect.Handler, client *birect.Client, srv EchoServer) *EchoClient {
+	RegisterEchoServer(server, srv)
+	return NewEchoClient(client)
+}
+`
+
+// typeCheck type-checks the generated code against the birect package and echoStubs.
+func typeCheck(t *testing.T, content string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for name, src := range map[string]string{"echo.birect.go": content, "echo.pb.go": echoStubs} {
+		// Absolute file names let the importer find the birect package relative to them
+		file, err := parser.ParseFile(fset, filepath.Join(wd, name), src, 0)
+		if err != nil {
+			t.Fatal(err, src)
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("github.com/example/echopb", fset, files, nil); err != nil {
+		t.Fatal(err, content)
+	}
+}
@@ -13,6 +13,24 @@ It has these top-level messages:
 	Message
 	Request
 	Response
+	Error
+	Attachment
+	MetadataPair
+	Cancel
+	StreamChunk
+	StreamEnd
+	StreamCredit
+	StreamOpen
+	StreamData
+	StreamClose
+	StreamDataCredit
+	StreamCancel
+	Subscribe
+	Unsubscribe
+	Publication
+	Ping
+	Pong
+	GoingAway
 */
 package wire
 
@@ -61,6 +79,21 @@ type Wrapper struct {
 	//	*Wrapper_Message
 	//	*Wrapper_Request
 	//	*Wrapper_Response
+	//	*Wrapper_Cancel
+	//	*Wrapper_StreamChunk
+	//	*Wrapper_StreamEnd
+	//	*Wrapper_StreamOpen
+	//	*Wrapper_StreamData
+	//	*Wrapper_StreamClose
+	//	*Wrapper_Subscribe
+	//	*Wrapper_Unsubscribe
+	//	*Wrapper_Publication
+	//	*Wrapper_Ping
+	//	*Wrapper_Pong
+	//	*Wrapper_GoingAway
+	//	*Wrapper_StreamCredit
+	//	*Wrapper_StreamDataCredit
+	//	*Wrapper_StreamCancel
 	Content isWrapper_Content `protobuf_oneof:"content"`
 }
 
@@ -82,10 +115,70 @@ type Wrapper_Request struct {
 type Wrapper_Response struct {
 	Response *Response `protobuf:"bytes,3,opt,name=response,oneof"`
 }
+type Wrapper_Cancel struct {
+	Cancel *Cancel `protobuf:"bytes,4,opt,name=cancel,oneof"`
+}
+type Wrapper_StreamChunk struct {
+	StreamChunk *StreamChunk `protobuf:"bytes,5,opt,name=stream_chunk,oneof"`
+}
+type Wrapper_StreamEnd struct {
+	StreamEnd *StreamEnd `protobuf:"bytes,6,opt,name=stream_end,oneof"`
+}
+type Wrapper_StreamOpen struct {
+	StreamOpen *StreamOpen `protobuf:"bytes,7,opt,name=stream_open,oneof"`
+}
+type Wrapper_StreamData struct {
+	StreamData *StreamData `protobuf:"bytes,8,opt,name=stream_data,oneof"`
+}
+type Wrapper_StreamClose struct {
+	StreamClose *StreamClose `protobuf:"bytes,9,opt,name=stream_close,oneof"`
+}
+type Wrapper_Subscribe struct {
+	Subscribe *Subscribe `protobuf:"bytes,10,opt,name=subscribe,oneof"`
+}
+type Wrapper_Unsubscribe struct {
+	Unsubscribe *Unsubscribe `protobuf:"bytes,11,opt,name=unsubscribe,oneof"`
+}
+type Wrapper_Publication struct {
+	Publication *Publication `protobuf:"bytes,12,opt,name=publication,oneof"`
+}
+type Wrapper_Ping struct {
+	Ping *Ping `protobuf:"bytes,13,opt,name=ping,oneof"`
+}
+type Wrapper_Pong struct {
+	Pong *Pong `protobuf:"bytes,14,opt,name=pong,oneof"`
+}
+type Wrapper_GoingAway struct {
+	GoingAway *GoingAway `protobuf:"bytes,15,opt,name=going_away,oneof"`
+}
+type Wrapper_StreamCredit struct {
+	StreamCredit *StreamCredit `protobuf:"bytes,16,opt,name=stream_credit,oneof"`
+}
+type Wrapper_StreamDataCredit struct {
+	StreamDataCredit *StreamDataCredit `protobuf:"bytes,17,opt,name=stream_data_credit,oneof"`
+}
+type Wrapper_StreamCancel struct {
+	StreamCancel *StreamCancel `protobuf:"bytes,18,opt,name=stream_cancel,oneof"`
+}
 
-func (*Wrapper_Message) isWrapper_Content()  {}
-func (*Wrapper_Request) isWrapper_Content()  {}
-func (*Wrapper_Response) isWrapper_Content() {}
+func (*Wrapper_Message) isWrapper_Content()          {}
+func (*Wrapper_Request) isWrapper_Content()          {}
+func (*Wrapper_Response) isWrapper_Content()         {}
+func (*Wrapper_Cancel) isWrapper_Content()           {}
+func (*Wrapper_StreamChunk) isWrapper_Content()      {}
+func (*Wrapper_StreamEnd) isWrapper_Content()        {}
+func (*Wrapper_StreamOpen) isWrapper_Content()       {}
+func (*Wrapper_StreamData) isWrapper_Content()       {}
+func (*Wrapper_StreamClose) isWrapper_Content()      {}
+func (*Wrapper_Subscribe) isWrapper_Content()        {}
+func (*Wrapper_Unsubscribe) isWrapper_Content()      {}
+func (*Wrapper_Publication) isWrapper_Content()      {}
+func (*Wrapper_Ping) isWrapper_Content()             {}
+func (*Wrapper_Pong) isWrapper_Content()             {}
+func (*Wrapper_GoingAway) isWrapper_Content()        {}
+func (*Wrapper_StreamCredit) isWrapper_Content()     {}
+func (*Wrapper_StreamDataCredit) isWrapper_Content() {}
+func (*Wrapper_StreamCancel) isWrapper_Content()     {}
 
 func (m *Wrapper) GetContent() isWrapper_Content {
 	if m != nil {
@@ -115,12 +208,132 @@ func (m *Wrapper) GetResponse() *Response {
 	return nil
 }
 
+func (m *Wrapper) GetCancel() *Cancel {
+	if x, ok := m.GetContent().(*Wrapper_Cancel); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamChunk() *StreamChunk {
+	if x, ok := m.GetContent().(*Wrapper_StreamChunk); ok {
+		return x.StreamChunk
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamEnd() *StreamEnd {
+	if x, ok := m.GetContent().(*Wrapper_StreamEnd); ok {
+		return x.StreamEnd
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamOpen() *StreamOpen {
+	if x, ok := m.GetContent().(*Wrapper_StreamOpen); ok {
+		return x.StreamOpen
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamData() *StreamData {
+	if x, ok := m.GetContent().(*Wrapper_StreamData); ok {
+		return x.StreamData
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamClose() *StreamClose {
+	if x, ok := m.GetContent().(*Wrapper_StreamClose); ok {
+		return x.StreamClose
+	}
+	return nil
+}
+
+func (m *Wrapper) GetSubscribe() *Subscribe {
+	if x, ok := m.GetContent().(*Wrapper_Subscribe); ok {
+		return x.Subscribe
+	}
+	return nil
+}
+
+func (m *Wrapper) GetUnsubscribe() *Unsubscribe {
+	if x, ok := m.GetContent().(*Wrapper_Unsubscribe); ok {
+		return x.Unsubscribe
+	}
+	return nil
+}
+
+func (m *Wrapper) GetPublication() *Publication {
+	if x, ok := m.GetContent().(*Wrapper_Publication); ok {
+		return x.Publication
+	}
+	return nil
+}
+
+func (m *Wrapper) GetPing() *Ping {
+	if x, ok := m.GetContent().(*Wrapper_Ping); ok {
+		return x.Ping
+	}
+	return nil
+}
+
+func (m *Wrapper) GetPong() *Pong {
+	if x, ok := m.GetContent().(*Wrapper_Pong); ok {
+		return x.Pong
+	}
+	return nil
+}
+
+func (m *Wrapper) GetGoingAway() *GoingAway {
+	if x, ok := m.GetContent().(*Wrapper_GoingAway); ok {
+		return x.GoingAway
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamCredit() *StreamCredit {
+	if x, ok := m.GetContent().(*Wrapper_StreamCredit); ok {
+		return x.StreamCredit
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamDataCredit() *StreamDataCredit {
+	if x, ok := m.GetContent().(*Wrapper_StreamDataCredit); ok {
+		return x.StreamDataCredit
+	}
+	return nil
+}
+
+func (m *Wrapper) GetStreamCancel() *StreamCancel {
+	if x, ok := m.GetContent().(*Wrapper_StreamCancel); ok {
+		return x.StreamCancel
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Wrapper) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Wrapper_OneofMarshaler, _Wrapper_OneofUnmarshaler, _Wrapper_OneofSizer, []interface{}{
 		(*Wrapper_Message)(nil),
 		(*Wrapper_Request)(nil),
 		(*Wrapper_Response)(nil),
+		(*Wrapper_Cancel)(nil),
+		(*Wrapper_StreamChunk)(nil),
+		(*Wrapper_StreamEnd)(nil),
+		(*Wrapper_StreamOpen)(nil),
+		(*Wrapper_StreamData)(nil),
+		(*Wrapper_StreamClose)(nil),
+		(*Wrapper_Subscribe)(nil),
+		(*Wrapper_Unsubscribe)(nil),
+		(*Wrapper_Publication)(nil),
+		(*Wrapper_Ping)(nil),
+		(*Wrapper_Pong)(nil),
+		(*Wrapper_GoingAway)(nil),
+		(*Wrapper_StreamCredit)(nil),
+		(*Wrapper_StreamDataCredit)(nil),
+		(*Wrapper_StreamCancel)(nil),
 	}
 }
 
@@ -143,6 +356,81 @@ func _Wrapper_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Response); err != nil {
 			return err
 		}
+	case *Wrapper_Cancel:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Cancel); err != nil {
+			return err
+		}
+	case *Wrapper_StreamChunk:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamChunk); err != nil {
+			return err
+		}
+	case *Wrapper_StreamEnd:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamEnd); err != nil {
+			return err
+		}
+	case *Wrapper_StreamOpen:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamOpen); err != nil {
+			return err
+		}
+	case *Wrapper_StreamData:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamData); err != nil {
+			return err
+		}
+	case *Wrapper_StreamClose:
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamClose); err != nil {
+			return err
+		}
+	case *Wrapper_Subscribe:
+		b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Subscribe); err != nil {
+			return err
+		}
+	case *Wrapper_Unsubscribe:
+		b.EncodeVarint(11<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Unsubscribe); err != nil {
+			return err
+		}
+	case *Wrapper_Publication:
+		b.EncodeVarint(12<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Publication); err != nil {
+			return err
+		}
+	case *Wrapper_Ping:
+		b.EncodeVarint(13<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Ping); err != nil {
+			return err
+		}
+	case *Wrapper_Pong:
+		b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Pong); err != nil {
+			return err
+		}
+	case *Wrapper_GoingAway:
+		b.EncodeVarint(15<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.GoingAway); err != nil {
+			return err
+		}
+	case *Wrapper_StreamCredit:
+		b.EncodeVarint(16<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamCredit); err != nil {
+			return err
+		}
+	case *Wrapper_StreamDataCredit:
+		b.EncodeVarint(17<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamDataCredit); err != nil {
+			return err
+		}
+	case *Wrapper_StreamCancel:
+		b.EncodeVarint(18<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.StreamCancel); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Wrapper.Content has unexpected type %T", x)
@@ -177,6 +465,126 @@ func _Wrapper_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Content = &Wrapper_Response{msg}
 		return true, err
+	case 4: // content.cancel
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Cancel)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Cancel{msg}
+		return true, err
+	case 5: // content.stream_chunk
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamChunk)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamChunk{msg}
+		return true, err
+	case 6: // content.stream_end
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamEnd)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamEnd{msg}
+		return true, err
+	case 7: // content.stream_open
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamOpen)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamOpen{msg}
+		return true, err
+	case 8: // content.stream_data
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamData)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamData{msg}
+		return true, err
+	case 9: // content.stream_close
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamClose)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamClose{msg}
+		return true, err
+	case 10: // content.subscribe
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Subscribe)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Subscribe{msg}
+		return true, err
+	case 11: // content.unsubscribe
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Unsubscribe)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Unsubscribe{msg}
+		return true, err
+	case 12: // content.publication
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Publication)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Publication{msg}
+		return true, err
+	case 13: // content.ping
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Ping)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Ping{msg}
+		return true, err
+	case 14: // content.pong
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Pong)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_Pong{msg}
+		return true, err
+	case 15: // content.going_away
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(GoingAway)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_GoingAway{msg}
+		return true, err
+	case 16: // content.stream_credit
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamCredit)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamCredit{msg}
+		return true, err
+	case 17: // content.stream_data_credit
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamDataCredit)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamDataCredit{msg}
+		return true, err
+	case 18: // content.stream_cancel
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(StreamCancel)
+		err := b.DecodeMessage(msg)
+		m.Content = &Wrapper_StreamCancel{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -201,6 +609,81 @@ func _Wrapper_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(3<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Wrapper_Cancel:
+		s := proto.Size(x.Cancel)
+		n += proto.SizeVarint(4<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamChunk:
+		s := proto.Size(x.StreamChunk)
+		n += proto.SizeVarint(5<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamEnd:
+		s := proto.Size(x.StreamEnd)
+		n += proto.SizeVarint(6<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamOpen:
+		s := proto.Size(x.StreamOpen)
+		n += proto.SizeVarint(7<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamData:
+		s := proto.Size(x.StreamData)
+		n += proto.SizeVarint(8<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamClose:
+		s := proto.Size(x.StreamClose)
+		n += proto.SizeVarint(9<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_Subscribe:
+		s := proto.Size(x.Subscribe)
+		n += proto.SizeVarint(10<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_Unsubscribe:
+		s := proto.Size(x.Unsubscribe)
+		n += proto.SizeVarint(11<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_Publication:
+		s := proto.Size(x.Publication)
+		n += proto.SizeVarint(12<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_Ping:
+		s := proto.Size(x.Ping)
+		n += proto.SizeVarint(13<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_Pong:
+		s := proto.Size(x.Pong)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_GoingAway:
+		s := proto.Size(x.GoingAway)
+		n += proto.SizeVarint(15<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamCredit:
+		s := proto.Size(x.StreamCredit)
+		n += proto.SizeVarint(16<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamDataCredit:
+		s := proto.Size(x.StreamDataCredit)
+		n += proto.SizeVarint(17<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Wrapper_StreamCancel:
+		s := proto.Size(x.StreamCancel)
+		n += proto.SizeVarint(18<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -211,8 +694,11 @@ func _Wrapper_OneofSizer(msg proto.Message) (n int) {
 type Message struct {
 	Type DataType `protobuf:"varint,1,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
 	// 2 left out
-	Name string `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
-	Data []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Name        string        `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
+	Data        []byte        `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Attachments []*Attachment `protobuf:"bytes,5,rep,name=attachments" json:"attachments,omitempty"`
+	// W3C trace context of the sender, e.g "00-<trace-id>-<parent-id>-<flags>"
+	Traceparent string `protobuf:"bytes,6,opt,name=traceparent" json:"traceparent,omitempty"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
@@ -220,11 +706,26 @@ func (m *Message) String() string            { return proto.CompactTextString(m)
 func (*Message) ProtoMessage()               {}
 func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
 
+func (m *Message) GetAttachments() []*Attachment {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
 type Request struct {
 	Type  DataType `protobuf:"varint,1,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
 	ReqId uint32   `protobuf:"varint,2,opt,name=req_id" json:"req_id,omitempty"`
 	Name  string   `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
 	Data  []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// Time left until the request deadline, when sent. 0 means no deadline.
+	TimeoutMs int64 `protobuf:"varint,5,opt,name=timeout_ms" json:"timeout_ms,omitempty"`
+	// Set for requests that get answered with a stream of chunks
+	Stream      bool            `protobuf:"varint,6,opt,name=stream" json:"stream,omitempty"`
+	Attachments []*Attachment   `protobuf:"bytes,7,rep,name=attachments" json:"attachments,omitempty"`
+	Metadata    []*MetadataPair `protobuf:"bytes,8,rep,name=metadata" json:"metadata,omitempty"`
+	// W3C trace context of the sender, e.g "00-<trace-id>-<parent-id>-<flags>"
+	Traceparent string `protobuf:"bytes,9,opt,name=traceparent" json:"traceparent,omitempty"`
 }
 
 func (m *Request) Reset()                    { *m = Request{} }
@@ -232,11 +733,28 @@ func (m *Request) String() string            { return proto.CompactTextString(m)
 func (*Request) ProtoMessage()               {}
 func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
+func (m *Request) GetAttachments() []*Attachment {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *Request) GetMetadata() []*MetadataPair {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 type Response struct {
-	Type    DataType `protobuf:"varint,1,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
-	ReqId   uint32   `protobuf:"varint,2,opt,name=req_id" json:"req_id,omitempty"`
-	IsError bool     `protobuf:"varint,3,opt,name=is_error" json:"is_error,omitempty"`
-	Data    []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Type        DataType      `protobuf:"varint,1,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
+	ReqId       uint32        `protobuf:"varint,2,opt,name=req_id" json:"req_id,omitempty"`
+	IsError     bool          `protobuf:"varint,3,opt,name=is_error" json:"is_error,omitempty"`
+	Data        []byte        `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Attachments []*Attachment `protobuf:"bytes,5,rep,name=attachments" json:"attachments,omitempty"`
+	// Set along with is_error. data then holds the public error message as Text
+	Error *Error `protobuf:"bytes,6,opt,name=error" json:"error,omitempty"`
 }
 
 func (m *Response) Reset()                    { *m = Response{} }
@@ -244,33 +762,329 @@ func (m *Response) String() string            { return proto.CompactTextString(m
 func (*Response) ProtoMessage()               {}
 func (*Response) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
+func (m *Response) GetAttachments() []*Attachment {
+	if m != nil {
+		return m.Attachments
+	}
+	return nil
+}
+
+func (m *Response) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+type Error struct {
+	Message      string   `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
+	Code         string   `protobuf:"bytes,2,opt,name=code" json:"code,omitempty"`
+	Retryable    bool     `protobuf:"varint,3,opt,name=retryable" json:"retryable,omitempty"`
+	RetryAfterMs int64    `protobuf:"varint,4,opt,name=retry_after_ms" json:"retry_after_ms,omitempty"`
+	DetailsType  DataType `protobuf:"varint,5,opt,name=details_type,enum=wire.DataType" json:"details_type,omitempty"`
+	Details      []byte   `protobuf:"bytes,6,opt,name=details,proto3" json:"details,omitempty"`
+}
+
+func (m *Error) Reset()                    { *m = Error{} }
+func (m *Error) String() string            { return proto.CompactTextString(m) }
+func (*Error) ProtoMessage()               {}
+func (*Error) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type Attachment struct {
+	Name string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Attachment) Reset()                    { *m = Attachment{} }
+func (m *Attachment) String() string            { return proto.CompactTextString(m) }
+func (*Attachment) ProtoMessage()               {}
+func (*Attachment) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type MetadataPair struct {
+	Key   string `protobuf:"bytes,1,opt,name=key" json:"key,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *MetadataPair) Reset()                    { *m = MetadataPair{} }
+func (m *MetadataPair) String() string            { return proto.CompactTextString(m) }
+func (*MetadataPair) ProtoMessage()               {}
+func (*MetadataPair) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+type Cancel struct {
+	ReqId uint32 `protobuf:"varint,1,opt,name=req_id" json:"req_id,omitempty"`
+}
+
+func (m *Cancel) Reset()                    { *m = Cancel{} }
+func (m *Cancel) String() string            { return proto.CompactTextString(m) }
+func (*Cancel) ProtoMessage()               {}
+func (*Cancel) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+type StreamChunk struct {
+	ReqId uint32   `protobuf:"varint,1,opt,name=req_id" json:"req_id,omitempty"`
+	Type  DataType `protobuf:"varint,2,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
+	Data  []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *StreamChunk) Reset()                    { *m = StreamChunk{} }
+func (m *StreamChunk) String() string            { return proto.CompactTextString(m) }
+func (*StreamChunk) ProtoMessage()               {}
+func (*StreamChunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type StreamEnd struct {
+	ReqId   uint32   `protobuf:"varint,1,opt,name=req_id" json:"req_id,omitempty"`
+	IsError bool     `protobuf:"varint,2,opt,name=is_error" json:"is_error,omitempty"`
+	Type    DataType `protobuf:"varint,3,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
+	Data    []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Error   *Error   `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *StreamEnd) Reset()                    { *m = StreamEnd{} }
+func (m *StreamEnd) String() string            { return proto.CompactTextString(m) }
+func (*StreamEnd) ProtoMessage()               {}
+func (*StreamEnd) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *StreamEnd) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+// Lets the handler of a streaming request send `credit` more chunks. Requesters grant
+// a buffer's worth of chunks up front, and more as their reader consumes them.
+type StreamCredit struct {
+	ReqId  uint32 `protobuf:"varint,1,opt,name=req_id" json:"req_id,omitempty"`
+	Credit uint32 `protobuf:"varint,2,opt,name=credit" json:"credit,omitempty"`
+}
+
+func (m *StreamCredit) Reset()                    { *m = StreamCredit{} }
+func (m *StreamCredit) String() string            { return proto.CompactTextString(m) }
+func (*StreamCredit) ProtoMessage()               {}
+func (*StreamCredit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+// Bidirectional streams are identified by the stream_id chosen by the side
+// that opened them, along with from_opener to tell the two sides' ids apart.
+type StreamOpen struct {
+	StreamId uint32 `protobuf:"varint,1,opt,name=stream_id" json:"stream_id,omitempty"`
+	Name     string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	// W3C trace context of the opener, e.g "00-<trace-id>-<parent-id>-<flags>"
+	Traceparent string `protobuf:"bytes,3,opt,name=traceparent" json:"traceparent,omitempty"`
+}
+
+func (m *StreamOpen) Reset()                    { *m = StreamOpen{} }
+func (m *StreamOpen) String() string            { return proto.CompactTextString(m) }
+func (*StreamOpen) ProtoMessage()               {}
+func (*StreamOpen) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type StreamData struct {
+	StreamId   uint32   `protobuf:"varint,1,opt,name=stream_id" json:"stream_id,omitempty"`
+	FromOpener bool     `protobuf:"varint,2,opt,name=from_opener" json:"from_opener,omitempty"`
+	Type       DataType `protobuf:"varint,3,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
+	Data       []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *StreamData) Reset()                    { *m = StreamData{} }
+func (m *StreamData) String() string            { return proto.CompactTextString(m) }
+func (*StreamData) ProtoMessage()               {}
+func (*StreamData) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+// Half-closes the stream in the direction of the sender
+type StreamClose struct {
+	StreamId   uint32 `protobuf:"varint,1,opt,name=stream_id" json:"stream_id,omitempty"`
+	FromOpener bool   `protobuf:"varint,2,opt,name=from_opener" json:"from_opener,omitempty"`
+	IsError    bool   `protobuf:"varint,3,opt,name=is_error" json:"is_error,omitempty"`
+	Data       []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Error      *Error `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+}
+
+func (m *StreamClose) Reset()                    { *m = StreamClose{} }
+func (m *StreamClose) String() string            { return proto.CompactTextString(m) }
+func (*StreamClose) ProtoMessage()               {}
+func (*StreamClose) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *StreamClose) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+// Lets the other side of a stream send `credit` more values, like StreamCredit.
+type StreamDataCredit struct {
+	StreamId   uint32 `protobuf:"varint,1,opt,name=stream_id" json:"stream_id,omitempty"`
+	FromOpener bool   `protobuf:"varint,2,opt,name=from_opener" json:"from_opener,omitempty"`
+	Credit     uint32 `protobuf:"varint,3,opt,name=credit" json:"credit,omitempty"`
+}
+
+func (m *StreamDataCredit) Reset()                    { *m = StreamDataCredit{} }
+func (m *StreamDataCredit) String() string            { return proto.CompactTextString(m) }
+func (*StreamDataCredit) ProtoMessage()               {}
+func (*StreamDataCredit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+// Closes the stream in both directions. The other side stops sending, and drops
+// values that it has not yet received.
+type StreamCancel struct {
+	StreamId   uint32 `protobuf:"varint,1,opt,name=stream_id" json:"stream_id,omitempty"`
+	FromOpener bool   `protobuf:"varint,2,opt,name=from_opener" json:"from_opener,omitempty"`
+}
+
+func (m *StreamCancel) Reset()                    { *m = StreamCancel{} }
+func (m *StreamCancel) String() string            { return proto.CompactTextString(m) }
+func (*StreamCancel) ProtoMessage()               {}
+func (*StreamCancel) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+// Subscribe and Unsubscribe are not acknowledged. The server handles them before
+// anything that the client sends afterwards.
+// Topics are dot-separated; see the Handler.Publish docs for the pattern syntax.
+type Subscribe struct {
+	Pattern string `protobuf:"bytes,2,opt,name=pattern" json:"pattern,omitempty"`
+}
+
+func (m *Subscribe) Reset()                    { *m = Subscribe{} }
+func (m *Subscribe) String() string            { return proto.CompactTextString(m) }
+func (*Subscribe) ProtoMessage()               {}
+func (*Subscribe) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+type Unsubscribe struct {
+	Pattern string `protobuf:"bytes,2,opt,name=pattern" json:"pattern,omitempty"`
+}
+
+func (m *Unsubscribe) Reset()                    { *m = Unsubscribe{} }
+func (m *Unsubscribe) String() string            { return proto.CompactTextString(m) }
+func (*Unsubscribe) ProtoMessage()               {}
+func (*Unsubscribe) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+type Publication struct {
+	Topic string   `protobuf:"bytes,1,opt,name=topic" json:"topic,omitempty"`
+	Type  DataType `protobuf:"varint,2,opt,name=type,enum=wire.DataType" json:"type,omitempty"`
+	Data  []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Publication) Reset()                    { *m = Publication{} }
+func (m *Publication) String() string            { return proto.CompactTextString(m) }
+func (*Publication) ProtoMessage()               {}
+func (*Publication) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+// Pings get answered with a Pong. Either side may send them to check that the
+// other side is still alive; see Heartbeat.
+type Ping struct {
+}
+
+func (m *Ping) Reset()                    { *m = Ping{} }
+func (m *Ping) String() string            { return proto.CompactTextString(m) }
+func (*Ping) ProtoMessage()               {}
+func (*Ping) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+type Pong struct {
+}
+
+func (m *Pong) Reset()                    { *m = Pong{} }
+func (m *Pong) String() string            { return proto.CompactTextString(m) }
+func (*Pong) ProtoMessage()               {}
+func (*Pong) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+// Sent by a server that is shutting down. The server finishes handling in-flight
+// requests, and then closes the connection.
+type GoingAway struct {
+}
+
+func (m *GoingAway) Reset()                    { *m = GoingAway{} }
+func (m *GoingAway) String() string            { return proto.CompactTextString(m) }
+func (*GoingAway) ProtoMessage()               {}
+func (*GoingAway) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
 func init() {
 	proto.RegisterType((*Wrapper)(nil), "wire.Wrapper")
 	proto.RegisterType((*Message)(nil), "wire.Message")
 	proto.RegisterType((*Request)(nil), "wire.Request")
 	proto.RegisterType((*Response)(nil), "wire.Response")
+	proto.RegisterType((*Error)(nil), "wire.Error")
+	proto.RegisterType((*Attachment)(nil), "wire.Attachment")
+	proto.RegisterType((*MetadataPair)(nil), "wire.MetadataPair")
+	proto.RegisterType((*Cancel)(nil), "wire.Cancel")
+	proto.RegisterType((*StreamChunk)(nil), "wire.StreamChunk")
+	proto.RegisterType((*StreamEnd)(nil), "wire.StreamEnd")
+	proto.RegisterType((*StreamCredit)(nil), "wire.StreamCredit")
+	proto.RegisterType((*StreamOpen)(nil), "wire.StreamOpen")
+	proto.RegisterType((*StreamData)(nil), "wire.StreamData")
+	proto.RegisterType((*StreamClose)(nil), "wire.StreamClose")
+	proto.RegisterType((*StreamDataCredit)(nil), "wire.StreamDataCredit")
+	proto.RegisterType((*StreamCancel)(nil), "wire.StreamCancel")
+	proto.RegisterType((*Subscribe)(nil), "wire.Subscribe")
+	proto.RegisterType((*Unsubscribe)(nil), "wire.Unsubscribe")
+	proto.RegisterType((*Publication)(nil), "wire.Publication")
+	proto.RegisterType((*Ping)(nil), "wire.Ping")
+	proto.RegisterType((*Pong)(nil), "wire.Pong")
+	proto.RegisterType((*GoingAway)(nil), "wire.GoingAway")
 	proto.RegisterEnum("wire.DataType", DataType_name, DataType_value)
 }
 
 var fileDescriptor0 = []byte{
-	// 289 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x92, 0x4f, 0x4b, 0x33, 0x31,
-	0x10, 0xc6, 0xbb, 0xed, 0xb6, 0xc9, 0xce, 0xfb, 0xb6, 0x2c, 0x01, 0x61, 0xbd, 0xc9, 0x9e, 0x54,
-	0xa4, 0x07, 0xfb, 0x0d, 0xc4, 0x82, 0x0a, 0x6e, 0x25, 0x56, 0x3c, 0x96, 0xd5, 0x1d, 0x64, 0xc1,
-	0x6e, 0x62, 0x12, 0x51, 0xbf, 0x89, 0x1f, 0xd7, 0xfc, 0xd9, 0x88, 0x07, 0x0f, 0x05, 0x6f, 0x4f,
-	0x9e, 0xf9, 0x65, 0x9e, 0x19, 0x18, 0x80, 0xb7, 0x56, 0xe1, 0x5c, 0x2a, 0x61, 0x04, 0x4b, 0x9d,
-	0x2e, 0x3f, 0x13, 0x20, 0xf7, 0xaa, 0x96, 0x12, 0x15, 0x3b, 0x02, 0xb2, 0x45, 0xad, 0xeb, 0x27,
-	0x2c, 0x92, 0x83, 0xe4, 0xf0, 0xdf, 0xe9, 0x74, 0xee, 0xf9, 0xeb, 0x60, 0x5e, 0x0c, 0x78, 0xac,
-	0x3b, 0x54, 0xe1, 0xcb, 0x2b, 0x6a, 0x53, 0x0c, 0x7f, 0xa2, 0x3c, 0x98, 0x0e, 0xed, 0xeb, 0xec,
-	0x04, 0xa8, 0x42, 0x2d, 0x45, 0xa7, 0xb1, 0x18, 0x79, 0x76, 0x16, 0xd9, 0xe0, 0x5a, 0xf8, 0x9b,
-	0x38, 0xcb, 0x80, 0x3c, 0x8a, 0xce, 0x60, 0x67, 0xca, 0x3b, 0x20, 0x7d, 0x32, 0x2b, 0x21, 0x35,
-	0x1f, 0x32, 0x8c, 0x35, 0x8b, 0xff, 0xcf, 0x6b, 0x53, 0xaf, 0xad, 0xcb, 0x7d, 0x8d, 0x31, 0x48,
-	0xbb, 0x7a, 0x1b, 0x32, 0x32, 0xee, 0xb5, 0xf3, 0x1a, 0x4b, 0x15, 0xa9, 0xf5, 0xfe, 0x73, 0xaf,
-	0xcb, 0x67, 0x20, 0xfd, 0x94, 0x3b, 0xb5, 0xdd, 0x83, 0x89, 0xdd, 0x64, 0xd3, 0x36, 0x7e, 0xd1,
-	0x29, 0x1f, 0xdb, 0xd7, 0x65, 0xb3, 0x73, 0x9a, 0x01, 0x1a, 0xf7, 0xfc, 0x4b, 0xdc, 0x3e, 0xd0,
-	0x56, 0x6f, 0x50, 0x29, 0xa1, 0x7c, 0x24, 0xe5, 0xa4, 0xd5, 0x4b, 0xf7, 0xfc, 0x2d, 0xf5, 0x78,
-	0x01, 0x34, 0xf6, 0x65, 0x14, 0xd2, 0x6a, 0x55, 0x2d, 0xf3, 0x81, 0x53, 0x6b, 0x7c, 0x37, 0x79,
-	0xe2, 0xd4, 0xd5, 0xed, 0xaa, 0xca, 0x87, 0x2c, 0x83, 0xf1, 0x8d, 0x3b, 0x87, 0x7c, 0xf4, 0x30,
-	0xf1, 0x77, 0xb1, 0xf8, 0x0a, 0x00, 0x00, 0xff, 0xff, 0x7f, 0x9c, 0xe1, 0xd4, 0x25, 0x02, 0x00,
-	0x00,
+	// 1044 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xac, 0x57, 0x5b, 0x6f, 0xe3, 0x44,
+	0x14, 0x8e, 0xe3, 0x5c, 0xec, 0x63, 0xb7, 0x9b, 0x1d, 0x41, 0x65, 0x04, 0x68, 0x8b, 0xc5, 0x5d,
+	0xa8, 0xc0, 0x16, 0x56, 0xe2, 0x81, 0x87, 0xb2, 0x14, 0x02, 0xa2, 0x17, 0xcd, 0x2e, 0xe2, 0x01,
+	0xa4, 0x68, 0xea, 0xcc, 0xba, 0xd6, 0x26, 0x63, 0x77, 0x66, 0x42, 0xc9, 0x2b, 0xbf, 0x82, 0x17,
+	0xc4, 0x33, 0xe2, 0x7f, 0xf0, 0xc2, 0x9f, 0x42, 0x73, 0xb1, 0x3d, 0x49, 0x2f, 0x0a, 0x5b, 0x9e,
+	0x32, 0xe7, 0x9c, 0xef, 0xf8, 0xdc, 0xcf, 0x4c, 0x00, 0x2e, 0x0b, 0x4e, 0xf7, 0x2a, 0x5e, 0xca,
+	0x12, 0xf5, 0xd4, 0x39, 0xfd, 0x63, 0x08, 0xc3, 0x1f, 0x38, 0xa9, 0x2a, 0xca, 0xd1, 0x7b, 0x30,
+	0x9c, 0x53, 0x21, 0x48, 0x4e, 0x13, 0x6f, 0xd7, 0x7b, 0x37, 0x7a, 0xb8, 0xb5, 0xa7, 0xf1, 0x47,
+	0x86, 0x39, 0xee, 0xe0, 0x5a, 0xae, 0xa0, 0x9c, 0x5e, 0x2c, 0xa8, 0x90, 0x49, 0xd7, 0x85, 0x62,
+	0xc3, 0x54, 0x50, 0x2b, 0x47, 0x1f, 0x40, 0xc0, 0xa9, 0xa8, 0x4a, 0x26, 0x68, 0xe2, 0x6b, 0xec,
+	0x76, 0x8d, 0x35, 0xdc, 0x71, 0x07, 0x37, 0x08, 0xf4, 0x36, 0x0c, 0x32, 0xc2, 0x32, 0x3a, 0x4b,
+	0x7a, 0x1a, 0x1b, 0x1b, 0xec, 0x63, 0xcd, 0x1b, 0x77, 0xb0, 0x95, 0xa2, 0x47, 0x10, 0x0b, 0xc9,
+	0x29, 0x99, 0x4f, 0xb2, 0xf3, 0x05, 0x7b, 0x9e, 0xf4, 0x35, 0xfa, 0xbe, 0x41, 0x3f, 0xd1, 0x92,
+	0xc7, 0x4a, 0x30, 0xee, 0xe0, 0x48, 0xb4, 0x24, 0xfa, 0x08, 0xc0, 0xea, 0x51, 0x36, 0x4d, 0x06,
+	0x5a, 0xeb, 0x9e, 0xab, 0x75, 0xc8, 0xa6, 0xe3, 0x0e, 0x0e, 0x45, 0x4d, 0xa0, 0x7d, 0xb0, 0x1f,
+	0x98, 0x94, 0x15, 0x65, 0xc9, 0x50, 0xab, 0x8c, 0x5c, 0x95, 0x93, 0x8a, 0xb2, 0x71, 0x07, 0x83,
+	0x68, 0x28, 0x47, 0x69, 0x4a, 0x24, 0x49, 0x82, 0xab, 0x4a, 0x5f, 0x12, 0x49, 0x5a, 0x25, 0x45,
+	0xb9, 0x31, 0xcd, 0x4a, 0x41, 0x93, 0xf0, 0x9a, 0x98, 0x94, 0xc0, 0x89, 0x49, 0x91, 0xe8, 0x43,
+	0x08, 0xc5, 0xe2, 0x4c, 0x64, 0xbc, 0x38, 0xa3, 0x09, 0xac, 0x84, 0x54, 0xb3, 0x75, 0x48, 0x35,
+	0x81, 0x3e, 0x85, 0x68, 0xc1, 0x5a, 0x95, 0xc8, 0xb5, 0xf3, 0x7d, 0x2b, 0x50, 0x76, 0x16, 0x6c,
+	0x45, 0xad, 0x5a, 0x9c, 0xcd, 0x8a, 0x8c, 0xc8, 0xa2, 0x64, 0x49, 0xec, 0xaa, 0x9d, 0xb6, 0x02,
+	0xa5, 0xe6, 0xe0, 0xd0, 0x2e, 0xf4, 0xaa, 0x82, 0xe5, 0xc9, 0x96, 0xc6, 0x83, 0xc5, 0x17, 0x2c,
+	0x1f, 0x77, 0xb0, 0x96, 0x68, 0x44, 0xc9, 0xf2, 0x64, 0x7b, 0x05, 0x51, 0x5a, 0x44, 0xc9, 0x72,
+	0x55, 0xb6, 0xbc, 0x2c, 0x58, 0x3e, 0x21, 0x97, 0x64, 0x99, 0xdc, 0x73, 0x63, 0xfc, 0x5a, 0xf1,
+	0x0f, 0x2e, 0xc9, 0x52, 0xc5, 0x98, 0xd7, 0x04, 0xfa, 0x0c, 0xb6, 0xea, 0x64, 0x72, 0x3a, 0x2d,
+	0x64, 0x32, 0xd2, 0x4a, 0x68, 0x25, 0x9b, 0x5a, 0x32, 0xee, 0xe0, 0x58, 0x38, 0x34, 0xfa, 0x0a,
+	0x90, 0x53, 0xbc, 0x5a, 0xff, 0xbe, 0xd6, 0xdf, 0x59, 0xaf, 0x61, 0xf3, 0x8d, 0x91, 0x58, 0xe3,
+	0xb9, 0x2e, 0x98, 0x96, 0x46, 0xd7, 0xb8, 0x50, 0x37, 0x76, 0x2c, 0x1c, 0xfa, 0x8b, 0x10, 0x86,
+	0x59, 0xc9, 0x24, 0x65, 0x32, 0xfd, 0xcb, 0x83, 0xa1, 0x9d, 0x40, 0x94, 0x42, 0x4f, 0x2e, 0x2b,
+	0x33, 0x9e, 0xdb, 0xf5, 0x1c, 0x29, 0x8b, 0x4f, 0x97, 0x15, 0xc5, 0x5a, 0x86, 0x10, 0xf4, 0x18,
+	0x99, 0x9b, 0x59, 0x0b, 0xb1, 0x3e, 0x2b, 0x9e, 0xee, 0x43, 0x35, 0x53, 0x31, 0xd6, 0x67, 0xf4,
+	0x10, 0x22, 0x22, 0x25, 0xc9, 0xce, 0xe7, 0x94, 0x49, 0x91, 0xf4, 0x77, 0xfd, 0xb6, 0x45, 0x0f,
+	0x1a, 0x01, 0x76, 0x41, 0x68, 0x17, 0x22, 0xc9, 0x49, 0x46, 0x2b, 0xc2, 0x29, 0x93, 0x7a, 0x7c,
+	0x42, 0xec, 0xb2, 0xd2, 0x3f, 0xbb, 0x30, 0xb4, 0x4b, 0x60, 0x23, 0x6f, 0x5f, 0x86, 0x01, 0xa7,
+	0x17, 0x93, 0x62, 0xaa, 0xf7, 0xc8, 0x16, 0xee, 0x73, 0x7a, 0xf1, 0xcd, 0x74, 0xe3, 0x20, 0x5e,
+	0x07, 0x90, 0xc5, 0x9c, 0x96, 0x0b, 0x39, 0x99, 0x0b, 0xbd, 0x04, 0x7c, 0x1c, 0x5a, 0xce, 0x91,
+	0x40, 0x3b, 0x30, 0x30, 0x69, 0xd5, 0xae, 0x06, 0xd8, 0x52, 0xeb, 0xb1, 0x0f, 0x37, 0x89, 0x7d,
+	0x0f, 0x82, 0x39, 0x95, 0xc4, 0xce, 0xb3, 0xdf, 0x16, 0xf2, 0xc8, 0x72, 0x4f, 0x49, 0xc1, 0x71,
+	0x83, 0x59, 0xcf, 0x55, 0x78, 0x35, 0x57, 0xff, 0x78, 0x10, 0xd4, 0x4b, 0xf0, 0x2e, 0xc9, 0x7a,
+	0x05, 0x82, 0x42, 0x4c, 0x28, 0xe7, 0x25, 0xd7, 0x09, 0x0b, 0xf0, 0xb0, 0x10, 0x87, 0x8a, 0xfc,
+	0xdf, 0x0a, 0xff, 0x06, 0xf4, 0xcd, 0xf7, 0xcd, 0xc6, 0x8c, 0x0c, 0x5a, 0xdb, 0xc0, 0x46, 0x92,
+	0xfe, 0xed, 0x41, 0xdf, 0x18, 0x4d, 0x56, 0xef, 0x91, 0xb0, 0xbd, 0x36, 0x10, 0xf4, 0xb2, 0x72,
+	0x4a, 0xb5, 0xfb, 0x21, 0xd6, 0x67, 0xf4, 0x1a, 0x84, 0x9c, 0x4a, 0xbe, 0x24, 0x67, 0x33, 0x6a,
+	0xdd, 0x6f, 0x19, 0xe8, 0x4d, 0xd8, 0xd6, 0xc4, 0x84, 0x3c, 0x93, 0x94, 0xab, 0x22, 0xf7, 0x74,
+	0x91, 0x63, 0xcd, 0x3d, 0x50, 0xcc, 0x23, 0x81, 0x3e, 0x86, 0x78, 0x4a, 0x25, 0x29, 0x66, 0x62,
+	0xa2, 0x93, 0xd8, 0xbf, 0x36, 0x89, 0x91, 0xc5, 0x28, 0x42, 0x39, 0x69, 0x49, 0x1d, 0x53, 0x8c,
+	0x6b, 0x32, 0xfd, 0x04, 0xa0, 0x4d, 0x43, 0xd3, 0x89, 0xde, 0x35, 0x9d, 0xd8, 0x6d, 0xb3, 0x9a,
+	0x3e, 0x82, 0xd8, 0x6d, 0x04, 0x34, 0x02, 0xff, 0x39, 0x5d, 0x5a, 0x35, 0x75, 0x44, 0x2f, 0x41,
+	0xff, 0x67, 0x32, 0x5b, 0xd4, 0xd1, 0x1b, 0x22, 0x7d, 0x00, 0x03, 0x33, 0xf3, 0x4e, 0x75, 0x3d,
+	0xa7, 0xba, 0xe9, 0x4f, 0x10, 0x39, 0xf7, 0xd9, 0x0d, 0xa8, 0xa6, 0x7d, 0xba, 0xb7, 0x6f, 0x06,
+	0xed, 0xb6, 0xef, 0xb8, 0xfd, 0x9b, 0x07, 0x61, 0x73, 0xf1, 0xdd, 0xf4, 0x71, 0xb7, 0xc1, 0xba,
+	0xab, 0x0d, 0x56, 0xdb, 0xf5, 0x37, 0xb0, 0xeb, 0x36, 0x61, 0xd3, 0x50, 0xfd, 0x1b, 0x1b, 0xea,
+	0x73, 0x88, 0xdd, 0x35, 0x7d, 0x93, 0x73, 0x3b, 0x30, 0xb0, 0x1b, 0xda, 0x0c, 0x85, 0xa5, 0xd2,
+	0x09, 0x40, 0x7b, 0x3d, 0xa3, 0x57, 0xc1, 0x5e, 0xe9, 0xad, 0x7e, 0x60, 0x18, 0xce, 0xb6, 0xe9,
+	0x3a, 0x35, 0x5e, 0x1b, 0x5f, 0xff, 0xea, 0xf8, 0xfe, 0xea, 0xd5, 0x16, 0xf4, 0xed, 0x7d, 0xab,
+	0x85, 0x07, 0x10, 0x3d, 0xe3, 0xa5, 0x79, 0x42, 0xd0, 0x3a, 0x89, 0xa0, 0x58, 0x27, 0x9a, 0xf3,
+	0xa2, 0x79, 0x4c, 0x7f, 0xf7, 0x9a, 0xf6, 0xd0, 0x6f, 0x81, 0xbb, 0x79, 0xf1, 0x1f, 0x37, 0xc9,
+	0x06, 0x45, 0x3c, 0x87, 0xd1, 0xfa, 0x5d, 0x79, 0x47, 0x1f, 0xdb, 0x7a, 0xfb, 0x2b, 0xf5, 0xfe,
+	0xae, 0x69, 0x17, 0x33, 0x4e, 0x77, 0xb2, 0x92, 0xbe, 0x05, 0x61, 0xf3, 0x78, 0x52, 0xbb, 0xa2,
+	0x22, 0x52, 0x52, 0xce, 0x6c, 0x8b, 0xd4, 0x64, 0xfa, 0x0e, 0x44, 0xce, 0x83, 0xe9, 0x16, 0xe0,
+	0x8f, 0x10, 0x39, 0x4f, 0x24, 0xb5, 0x0b, 0x64, 0x59, 0x15, 0x99, 0xdd, 0x0f, 0x86, 0x78, 0xe1,
+	0x21, 0x1e, 0x40, 0x4f, 0xbd, 0xa7, 0xf4, 0x6f, 0xc9, 0xf2, 0x34, 0x82, 0xb0, 0x79, 0x15, 0xbd,
+	0xbf, 0x0f, 0x41, 0xfd, 0x09, 0x14, 0x40, 0xef, 0xf8, 0xe4, 0xf8, 0x70, 0xd4, 0x51, 0xa7, 0xa7,
+	0xf4, 0x17, 0x39, 0xf2, 0xd4, 0xe9, 0xdb, 0x27, 0x27, 0xc7, 0xa3, 0x2e, 0x0a, 0xa1, 0x7f, 0xaa,
+	0xfe, 0x1a, 0x8c, 0xfc, 0xb3, 0x81, 0xfe, 0x8f, 0xb0, 0xff, 0xef, 0x00, 0xc6, 0x70, 0x48, 0x4b,
+	0x31, 0x0c, 0x00, 0x00,
 }